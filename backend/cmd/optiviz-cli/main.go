@@ -39,6 +39,34 @@ func main() {
 		rules.NewSeqScanRule(),
 		rules.NewLeadingWildcardRule(),
 		rules.NewFunctionOnColumnRule(),
+		rules.NewRowEstimateRule(),
+		rules.NewDiskSpillRule(),
+		rules.NewFilterSelectivityRule(),
+		rules.NewNestedLoopRule(),
+		rules.NewSelectStarRule(),
+		rules.NewNotInSubqueryRule(),
+		rules.NewOffsetPaginationRule(),
+		rules.NewCartesianJoinRule(),
+		rules.NewCorrelatedSubPlanRule(),
+		rules.NewBufferUsageRule(),
+		rules.NewIndexOnlyHeapFetchRule(),
+		rules.NewParallelQueryRule(),
+		rules.NewPlanningOverheadRule(),
+		rules.NewColumnCastRule(),
+		rules.NewArithmeticOnColumnRule(),
+		rules.NewOrPredicateRule(),
+		rules.NewLargeInListRule(),
+		rules.NewRedundantDedupRule(),
+		rules.NewOrderByRandomRule(),
+		rules.NewUnorderedLimitRule(),
+		rules.NewCTEMaterializationRule(),
+		rules.NewTriggerOverheadRule(),
+		rules.NewDangerousDMLRule(),
+		rules.NewIndexConcurrentlyRule(),
+		rules.NewNotNullDefaultRule(),
+		rules.NewColumnTypeChangeRule(),
+		rules.NewConstraintValidationRule(),
+		rules.NewRenameObjectRule(),
 	)
 	svc := analyzer.New(engine)
 
@@ -111,6 +139,9 @@ func printHuman(resp types.AnalyzeResponse, showAST, showPlan bool, astDepth int
 			if s.Recommendation != "" {
 				fmt.Printf("   * %s\n", s.Recommendation)
 			}
+			if s.Rewrite != "" {
+				fmt.Printf("   > %s\n", s.Rewrite)
+			}
 			fmt.Println()
 		}
 	}
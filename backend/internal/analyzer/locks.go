@@ -0,0 +1,425 @@
+package analyzer
+
+import (
+	"github.com/evgeny/sql-opti-viz/backend/internal/rules"
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type lockMode int
+
+// Lock modes in PostgreSQL's order of strength; LOCK TABLE uses the same
+// numbering in its parse tree.
+const (
+	accessShareLock lockMode = iota + 1
+	rowShareLock
+	rowExclusiveLock
+	shareUpdateExclusiveLock
+	shareLock
+	shareRowExclusiveLock
+	exclusiveLock
+	accessExclusiveLock
+)
+
+var lockModeNames = map[lockMode]string{
+	accessShareLock:          "AccessShareLock",
+	rowShareLock:             "RowShareLock",
+	rowExclusiveLock:         "RowExclusiveLock",
+	shareUpdateExclusiveLock: "ShareUpdateExclusiveLock",
+	shareLock:                "ShareLock",
+	shareRowExclusiveLock:    "ShareRowExclusiveLock",
+	exclusiveLock:            "ExclusiveLock",
+	accessExclusiveLock:      "AccessExclusiveLock",
+}
+
+// alterTableLockModes lists the ALTER TABLE subcommands that take less than
+// an AccessExclusiveLock.
+var alterTableLockModes = map[string]lockMode{
+	"AT_ValidateConstraint": shareUpdateExclusiveLock,
+	"AT_SetStatistics":      shareUpdateExclusiveLock,
+	"AT_SetOptions":         shareUpdateExclusiveLock,
+	"AT_ResetOptions":       shareUpdateExclusiveLock,
+	"AT_SetRelOptions":      shareUpdateExclusiveLock,
+	"AT_ResetRelOptions":    shareUpdateExclusiveLock,
+	"AT_ClusterOn":          shareUpdateExclusiveLock,
+	"AT_DropCluster":        shareUpdateExclusiveLock,
+	"AT_AttachPartition":    shareUpdateExclusiveLock,
+	"AT_EnableTrig":         shareRowExclusiveLock,
+	"AT_EnableAlwaysTrig":   shareRowExclusiveLock,
+	"AT_EnableReplicaTrig":  shareRowExclusiveLock,
+	"AT_EnableTrigAll":      shareRowExclusiveLock,
+	"AT_EnableTrigUser":     shareRowExclusiveLock,
+	"AT_DisableTrig":        shareRowExclusiveLock,
+	"AT_DisableTrigAll":     shareRowExclusiveLock,
+	"AT_DisableTrigUser":    shareRowExclusiveLock,
+}
+
+var rowLockStrengths = map[string]string{
+	"LCS_FORKEYSHARE":    "FOR KEY SHARE",
+	"LCS_FORSHARE":       "FOR SHARE",
+	"LCS_FORNOKEYUPDATE": "FOR NO KEY UPDATE",
+	"LCS_FORUPDATE":      "FOR UPDATE",
+}
+
+const queueLockWarning = "Concurrent workers running this query wait on each other's locked rows. Add SKIP LOCKED to take the next free rows, or NOWAIT to fail fast instead of queueing."
+
+type statementLocks struct {
+	statement string
+	relations []string
+	modes     map[string]lockMode
+	rowLocks  map[string]string
+	warnings  map[string]string
+	ctes      map[string]struct{}
+}
+
+func (s *statementLocks) add(relation string, mode lockMode) {
+	if relation == "" {
+		return
+	}
+	current, ok := s.modes[relation]
+	if !ok {
+		s.relations = append(s.relations, relation)
+	}
+	if mode > current {
+		s.modes[relation] = mode
+	}
+}
+
+// addReads takes an AccessShareLock on every relation the node reads,
+// skipping references to CTEs. Relations named in FOR UPDATE OF are
+// aliases, not extra reads.
+func (s *statementLocks) addReads(node any) {
+	rules.WalkAST(withoutLockingClauses(node), func(m map[string]any) {
+		rangeVar, ok := m["RangeVar"].(map[string]any)
+		if !ok {
+			return
+		}
+		if _, hasSchema := rangeVar["schemaname"]; !hasSchema {
+			if _, isCTE := s.ctes[rules.QualifiedRelationName(rangeVar)]; isCTE {
+				return
+			}
+		}
+		s.add(rules.QualifiedRelationName(rangeVar), accessShareLock)
+	})
+}
+
+// analyzeLocks maps every statement in the AST to the table-level locks it
+// acquires on each relation it touches.
+func analyzeLocks(ast map[string]any) []types.LockInfo {
+	locks := make([]types.LockInfo, 0)
+
+	stmts, _ := ast["stmts"].([]any)
+	for _, raw := range stmts {
+		entry, _ := raw.(map[string]any)
+		stmt, _ := entry["stmt"].(map[string]any)
+		for kind, body := range stmt {
+			node, ok := body.(map[string]any)
+			if !ok {
+				continue
+			}
+			collected := collectStatementLocks(kind, node)
+			if collected == nil {
+				continue
+			}
+			for _, relation := range collected.relations {
+				locks = append(locks, types.LockInfo{
+					Statement: collected.statement,
+					Relation:  relation,
+					Mode:      lockModeNames[collected.modes[relation]],
+					RowLocks:  collected.rowLocks[relation],
+					Warning:   collected.warnings[relation],
+				})
+			}
+		}
+	}
+
+	return locks
+}
+
+func collectStatementLocks(kind string, stmt map[string]any) *statementLocks {
+	locks := &statementLocks{
+		modes:    make(map[string]lockMode),
+		rowLocks: make(map[string]string),
+		warnings: make(map[string]string),
+		ctes:     cteNames(stmt),
+	}
+
+	switch kind {
+	case "SelectStmt":
+		locks.statement = "SELECT"
+		locks.addReads(stmt)
+		addRowLocks(locks, kind, stmt)
+	case "InsertStmt", "UpdateStmt", "DeleteStmt", "MergeStmt":
+		locks.statement = map[string]string{
+			"InsertStmt": "INSERT",
+			"UpdateStmt": "UPDATE",
+			"DeleteStmt": "DELETE",
+			"MergeStmt":  "MERGE",
+		}[kind]
+		relation, _ := stmt["relation"].(map[string]any)
+		locks.add(rules.QualifiedRelationName(relation), rowExclusiveLock)
+		locks.addReads(stmt)
+		addRowLocks(locks, kind, stmt)
+	case "TruncateStmt":
+		locks.statement = "TRUNCATE"
+		addRangeVars(locks, stmt["relations"], accessExclusiveLock)
+	case "LockStmt":
+		locks.statement = "LOCK TABLE"
+		mode, _ := stmt["mode"].(float64)
+		addRangeVars(locks, stmt["relations"], lockMode(mode))
+	case "AlterTableStmt":
+		if objType, _ := stmt["objtype"].(string); objType != "OBJECT_TABLE" {
+			return nil
+		}
+		locks.statement = "ALTER TABLE"
+		relation, _ := stmt["relation"].(map[string]any)
+		table := rules.QualifiedRelationName(relation)
+		cmds, _ := stmt["cmds"].([]any)
+		for _, raw := range cmds {
+			cmd, _ := rules.ChildNode(raw, "AlterTableCmd")
+			subtype, _ := cmd["subtype"].(string)
+			mode, ok := alterTableLockModes[subtype]
+			if !ok {
+				mode = accessExclusiveLock
+			}
+			if constraint, ok := rules.ChildNode(cmd["def"], "Constraint"); ok && subtype == "AT_AddConstraint" {
+				if contype, _ := constraint["contype"].(string); contype == "CONSTR_FOREIGN" {
+					mode = shareRowExclusiveLock
+					referenced, _ := constraint["pktable"].(map[string]any)
+					locks.add(table, mode)
+					locks.add(rules.QualifiedRelationName(referenced), shareRowExclusiveLock)
+					continue
+				}
+			}
+			locks.add(table, mode)
+		}
+	case "RenameStmt":
+		relation, ok := stmt["relation"].(map[string]any)
+		if !ok {
+			return nil
+		}
+		locks.statement = "RENAME"
+		locks.add(rules.QualifiedRelationName(relation), accessExclusiveLock)
+	case "IndexStmt":
+		locks.statement = "CREATE INDEX"
+		mode := shareLock
+		if concurrent, _ := stmt["concurrent"].(bool); concurrent {
+			locks.statement, mode = "CREATE INDEX CONCURRENTLY", shareUpdateExclusiveLock
+		}
+		relation, _ := stmt["relation"].(map[string]any)
+		locks.add(rules.QualifiedRelationName(relation), mode)
+	case "DropStmt":
+		removeType, _ := stmt["removeType"].(string)
+		mode := accessExclusiveLock
+		switch removeType {
+		case "OBJECT_TABLE":
+			locks.statement = "DROP TABLE"
+		case "OBJECT_INDEX":
+			locks.statement = "DROP INDEX"
+			if concurrent, _ := stmt["concurrent"].(bool); concurrent {
+				locks.statement, mode = "DROP INDEX CONCURRENTLY", shareUpdateExclusiveLock
+			}
+		default:
+			return nil
+		}
+		objects, _ := stmt["objects"].([]any)
+		for _, object := range objects {
+			locks.add(qualifiedListName(object), mode)
+		}
+	case "VacuumStmt":
+		locks.statement = "ANALYZE"
+		if vacuum, _ := stmt["is_vacuumcmd"].(bool); vacuum {
+			locks.statement = "VACUUM"
+		}
+		mode := shareUpdateExclusiveLock
+		options, _ := stmt["options"].([]any)
+		for _, option := range options {
+			if defElem, ok := rules.ChildNode(option, "DefElem"); ok && defElem["defname"] == "full" {
+				locks.statement, mode = "VACUUM FULL", accessExclusiveLock
+			}
+		}
+		rels, _ := stmt["rels"].([]any)
+		for _, rel := range rels {
+			vacuumRel, _ := rules.ChildNode(rel, "VacuumRelation")
+			relation, _ := vacuumRel["relation"].(map[string]any)
+			locks.add(rules.QualifiedRelationName(relation), mode)
+		}
+	case "ClusterStmt":
+		locks.statement = "CLUSTER"
+		relation, _ := stmt["relation"].(map[string]any)
+		locks.add(rules.QualifiedRelationName(relation), accessExclusiveLock)
+	case "RefreshMatViewStmt":
+		locks.statement = "REFRESH MATERIALIZED VIEW"
+		mode := accessExclusiveLock
+		if concurrent, _ := stmt["concurrent"].(bool); concurrent {
+			locks.statement, mode = "REFRESH MATERIALIZED VIEW CONCURRENTLY", exclusiveLock
+		}
+		relation, _ := stmt["relation"].(map[string]any)
+		locks.add(rules.QualifiedRelationName(relation), mode)
+	case "CreateTrigStmt":
+		locks.statement = "CREATE TRIGGER"
+		relation, _ := stmt["relation"].(map[string]any)
+		locks.add(rules.QualifiedRelationName(relation), shareRowExclusiveLock)
+	default:
+		return nil
+	}
+
+	return locks
+}
+
+// addRowLocks records FOR UPDATE/SHARE clauses on the statement and on every
+// SELECT nested in it, such as sublinks, CTEs and subqueries in FROM. Locked
+// rows also take a RowShareLock on their table.
+func addRowLocks(locks *statementLocks, kind string, stmt map[string]any) {
+	rules.WalkAST(map[string]any{kind: stmt}, func(m map[string]any) {
+		if selectStmt, ok := m["SelectStmt"].(map[string]any); ok {
+			addLockingClauses(locks, selectStmt)
+		}
+	})
+}
+
+func addLockingClauses(locks *statementLocks, stmt map[string]any) {
+	clauses, _ := stmt["lockingClause"].([]any)
+	if len(clauses) == 0 {
+		return
+	}
+
+	aliases, relations := fromAliases(stmt)
+	_, hasLimit := stmt["limitCount"]
+	_, hasOrder := stmt["sortClause"]
+	_, hasWhere := stmt["whereClause"]
+	queueLike := hasLimit && (hasOrder || hasWhere)
+
+	for _, raw := range clauses {
+		clause, ok := rules.ChildNode(raw, "LockingClause")
+		if !ok {
+			continue
+		}
+		strength, _ := clause["strength"].(string)
+		rowLock := rowLockStrengths[strength]
+		if rowLock == "" {
+			continue
+		}
+		if locks.statement == "SELECT" {
+			locks.statement = "SELECT " + rowLock
+		}
+
+		targets := make([]string, 0)
+		lockedRels, _ := clause["lockedRels"].([]any)
+		for _, rel := range lockedRels {
+			if rangeVar, ok := rules.ChildNode(rel, "RangeVar"); ok {
+				name := rules.QualifiedRelationName(rangeVar)
+				if resolved, ok := aliases[name]; ok {
+					name = resolved
+				}
+				targets = append(targets, name)
+			}
+		}
+		if len(targets) == 0 {
+			targets = relations
+		}
+
+		waitPolicy, _ := clause["waitPolicy"].(string)
+		blocking := waitPolicy == "" || waitPolicy == "LockWaitBlock"
+		exclusive := strength == "LCS_FORUPDATE" || strength == "LCS_FORNOKEYUPDATE"
+		for _, relation := range targets {
+			if _, isCTE := locks.ctes[relation]; isCTE {
+				continue
+			}
+			locks.add(relation, rowShareLock)
+			locks.rowLocks[relation] = rowLock
+			if waitPolicy == "LockWaitSkip" {
+				locks.rowLocks[relation] += " SKIP LOCKED"
+			} else if waitPolicy == "LockWaitError" {
+				locks.rowLocks[relation] += " NOWAIT"
+			}
+			if blocking && exclusive && queueLike {
+				locks.warnings[relation] = queueLockWarning
+			}
+		}
+	}
+}
+
+// withoutLockingClauses copies node without its FOR UPDATE/SHARE clauses.
+func withoutLockingClauses(node any) any {
+	switch typed := node.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(typed))
+		for key, value := range typed {
+			if key != "lockingClause" {
+				copied[key] = withoutLockingClauses(value)
+			}
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(typed))
+		for i, item := range typed {
+			copied[i] = withoutLockingClauses(item)
+		}
+		return copied
+	default:
+		return node
+	}
+}
+
+func addRangeVars(locks *statementLocks, node any, mode lockMode) {
+	items, _ := node.([]any)
+	for _, item := range items {
+		if rangeVar, ok := rules.ChildNode(item, "RangeVar"); ok {
+			locks.add(rules.QualifiedRelationName(rangeVar), mode)
+		}
+	}
+}
+
+// fromAliases maps the aliases in FROM to relation names and lists the
+// relations in order.
+func fromAliases(stmt map[string]any) (map[string]string, []string) {
+	aliases := make(map[string]string)
+	relations := make([]string, 0)
+	from, _ := stmt["fromClause"].([]any)
+	rules.WalkAST(from, func(m map[string]any) {
+		rangeVar, ok := m["RangeVar"].(map[string]any)
+		if !ok {
+			return
+		}
+		name := rules.QualifiedRelationName(rangeVar)
+		alias := name
+		if aliasNode, ok := rangeVar["alias"].(map[string]any); ok {
+			if aliasName, _ := aliasNode["aliasname"].(string); aliasName != "" {
+				alias = aliasName
+			}
+		}
+		aliases[alias] = name
+		relations = append(relations, name)
+	})
+	return aliases, relations
+}
+
+func cteNames(stmt map[string]any) map[string]struct{} {
+	names := make(map[string]struct{})
+	rules.WalkAST(stmt, func(m map[string]any) {
+		if cte, ok := m["CommonTableExpr"].(map[string]any); ok {
+			if name, _ := cte["ctename"].(string); name != "" {
+				names[name] = struct{}{}
+			}
+		}
+	})
+	return names
+}
+
+func qualifiedListName(node any) string {
+	list, ok := rules.ChildNode(node, "List")
+	if !ok {
+		return ""
+	}
+	items, _ := list["items"].([]any)
+	name := ""
+	for _, item := range items {
+		str, _ := rules.ChildNode(item, "String")
+		part, _ := str["sval"].(string)
+		if name != "" {
+			name += "."
+		}
+		name += part
+	}
+	return name
+}
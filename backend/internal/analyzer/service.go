@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
@@ -32,18 +33,30 @@ func (s *Service) Analyze(ctx context.Context, req types.AnalyzeRequest) (types.
 		return types.AnalyzeResponse{}, fmt.Errorf("parse AST: %w", err)
 	}
 
-	plan, err := s.obtainPlan(ctx, req)
+	mode, err := explainModeFor(ctx, req, ast)
 	if err != nil {
 		return types.AnalyzeResponse{}, err
 	}
 
+	plan, conn, err := s.obtainPlan(ctx, req, mode)
+	if err != nil {
+		return types.AnalyzeResponse{}, err
+	}
+	if conn != nil {
+		defer conn.Close(ctx)
+	}
+
 	suggestions := []types.Suggestion{}
 	if s.engine != nil {
-		suggestions, err = s.engine.Evaluate(ctx, rules.Input{
+		input := rules.Input{
 			AST:     ast,
 			Plan:    plan,
 			Request: req,
-		})
+		}
+		if conn != nil {
+			input.DB = conn
+		}
+		suggestions, err = s.engine.Evaluate(ctx, input)
 		if err != nil {
 			return types.AnalyzeResponse{}, fmt.Errorf("run rule engine: %w", err)
 		}
@@ -53,6 +66,7 @@ func (s *Service) Analyze(ctx context.Context, req types.AnalyzeRequest) (types.
 		AST:         ast,
 		ExplainPlan: plan,
 		Suggestions: suggestions,
+		Locks:       analyzeLocks(ast),
 	}, nil
 }
 
@@ -70,23 +84,131 @@ func parseAST(query string) (map[string]any, error) {
 	return ast, nil
 }
 
-func (s *Service) obtainPlan(ctx context.Context, req types.AnalyzeRequest) (map[string]any, error) {
+// explainMode says how connected mode may capture a plan for a statement.
+type explainMode int
+
+const (
+	// explainSkip analyzes the statement without a plan.
+	explainSkip explainMode = iota
+	// explainAnalyze executes the statement to collect actual row counts.
+	explainAnalyze
+	// explainAnalyzeRollback executes a data-modifying statement inside a
+	// transaction that is rolled back, so its writes are discarded while the
+	// plan still reports actual rows and trigger timings.
+	explainAnalyzeRollback
+)
+
+// explainModeFor decides how the statement may be explained before the
+// database is touched. EXPLAIN ANALYZE executes its statement, so DML runs
+// inside BEGIN ... ROLLBACK, and DML the DangerousDML rule flags from the AST
+// alone is never sent to the server. Sequence increments are not rolled back.
+func explainModeFor(ctx context.Context, req types.AnalyzeRequest, ast map[string]any) (explainMode, error) {
+	if !isExplainable(ast) {
+		return explainSkip, nil
+	}
+	if !modifiesData(ast) {
+		return explainAnalyze, nil
+	}
+
+	dangerous, err := rules.NewDangerousDMLRule().Apply(ctx, rules.Input{AST: ast, Request: req})
+	if err != nil {
+		return explainSkip, fmt.Errorf("check dangerous DML: %w", err)
+	}
+	if len(dangerous) > 0 {
+		return explainSkip, nil
+	}
+	return explainAnalyzeRollback, nil
+}
+
+// obtainPlan returns the plan and, in connected mode, the open connection it
+// was captured on so rules can query statistics; the caller closes it.
+// Statements EXPLAIN cannot run, such as DDL, are analyzed without a plan.
+func (s *Service) obtainPlan(ctx context.Context, req types.AnalyzeRequest, mode explainMode) (map[string]any, *pgx.Conn, error) {
 	switch req.Mode {
 	case types.ModeConnected:
 		if strings.TrimSpace(req.ConnectionString) == "" {
-			return nil, errors.New("connection_string is required for connected mode")
+			return nil, nil, errors.New("connection_string is required for connected mode")
+		}
+		conn, err := pgx.Connect(ctx, req.ConnectionString)
+		if err != nil {
+			return nil, nil, err
+		}
+		if mode == explainSkip {
+			return nil, conn, nil
 		}
-		return runExplain(ctx, req.ConnectionString, req.Query)
+		plan, err := runExplain(ctx, conn, req.Query, mode)
+		if err != nil {
+			conn.Close(ctx)
+			return nil, nil, err
+		}
+		return plan, conn, nil
 	case types.ModeManual:
+		if len(req.ExplainJSON) == 0 && mode == explainSkip {
+			return nil, nil, nil
+		}
 		if len(req.ExplainJSON) == 0 {
-			return nil, errors.New("explain_json is required for manual mode")
+			return nil, nil, errors.New("explain_json is required for manual mode")
 		}
-		return decodePlan(req.ExplainJSON)
+		plan, err := decodePlan(req.ExplainJSON)
+		return plan, nil, err
 	default:
-		return nil, fmt.Errorf("unsupported mode %q", req.Mode)
+		return nil, nil, fmt.Errorf("unsupported mode %q", req.Mode)
 	}
 }
 
+var explainableStmts = []string{
+	"SelectStmt",
+	"InsertStmt",
+	"UpdateStmt",
+	"DeleteStmt",
+	"MergeStmt",
+	"CreateTableAsStmt",
+	"ExecuteStmt",
+	"DeclareCursorStmt",
+}
+
+func isExplainable(ast map[string]any) bool {
+	stmts, _ := ast["stmts"].([]any)
+	for _, raw := range stmts {
+		entry, _ := raw.(map[string]any)
+		stmt, _ := entry["stmt"].(map[string]any)
+		if !slices.ContainsFunc(explainableStmts, func(kind string) bool {
+			_, ok := stmt[kind]
+			return ok
+		}) {
+			return false
+		}
+	}
+	return len(stmts) > 0
+}
+
+// dataModifyingNodes are the AST keys of statements that write when run;
+// intoClause covers SELECT INTO. EXECUTE is included because the prepared
+// statement it runs is unknown.
+var dataModifyingNodes = []string{
+	"InsertStmt",
+	"UpdateStmt",
+	"DeleteStmt",
+	"MergeStmt",
+	"CreateTableAsStmt",
+	"ExecuteStmt",
+	"intoClause",
+}
+
+// modifiesData reports whether running the statements would write, including
+// data-modifying CTEs attached to a SELECT.
+func modifiesData(ast map[string]any) bool {
+	found := false
+	rules.WalkAST(ast, func(m map[string]any) {
+		for _, key := range dataModifyingNodes {
+			if _, ok := m[key]; ok {
+				found = true
+			}
+		}
+	})
+	return found
+}
+
 func decodePlan(raw json.RawMessage) (map[string]any, error) {
 	var payload any
 	if err := json.Unmarshal(raw, &payload); err != nil {
@@ -95,14 +217,26 @@ func decodePlan(raw json.RawMessage) (map[string]any, error) {
 	return normalizePlan(payload)
 }
 
-func runExplain(ctx context.Context, connStr, query string) (map[string]any, error) {
-	conn, err := pgx.Connect(ctx, connStr)
+func runExplain(ctx context.Context, conn *pgx.Conn, query string, mode explainMode) (map[string]any, error) {
+	explainQuery := fmt.Sprintf("EXPLAIN (FORMAT JSON, COSTS, ANALYZE, BUFFERS) %s", query)
+	if mode != explainAnalyzeRollback {
+		return queryPlan(ctx, conn, explainQuery)
+	}
+
+	tx, err := conn.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close(ctx)
+	// The writes are discarded whether or not the rollback reports an error.
+	defer tx.Rollback(ctx)
+	return queryPlan(ctx, tx, explainQuery)
+}
 
-	explainQuery := fmt.Sprintf("EXPLAIN (FORMAT JSON, COSTS, ANALYZE, BUFFERS) %s", query)
+type planQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func queryPlan(ctx context.Context, conn planQuerier, explainQuery string) (map[string]any, error) {
 	rows, err := conn.Query(ctx, explainQuery)
 	if err != nil {
 		return nil, err
@@ -3,6 +3,7 @@ package analyzer
 import (
 	"context"
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/evgeny/sql-opti-viz/backend/internal/rules"
@@ -87,3 +88,130 @@ func TestAnalyzeConnectedRequiresConnectionString(t *testing.T) {
 		t.Fatalf("expected error when connection string missing in connected mode")
 	}
 }
+
+func TestAnalyzeManualModeDDLWithoutPlan(t *testing.T) {
+	service := New(rules.NewEngine(rules.NewIndexConcurrentlyRule()))
+
+	req := types.AnalyzeRequest{
+		Mode:  types.ModeManual,
+		Query: "CREATE INDEX orders_user_id_idx ON orders (user_id);",
+	}
+
+	resp, err := service.Analyze(context.Background(), req)
+	if err != nil {
+		t.Fatalf("analyze DDL: %v", err)
+	}
+
+	if plan, _ := resp.ExplainPlan.(map[string]any); plan != nil {
+		t.Fatalf("expected no explain plan for DDL")
+	}
+
+	if len(resp.Suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(resp.Suggestions))
+	}
+}
+
+func TestExplainModeFor(t *testing.T) {
+	tests := []struct {
+		query string
+		mode  explainMode
+	}{
+		{query: "SELECT * FROM orders WHERE id = 1", mode: explainAnalyze},
+		{query: "SELECT * FROM jobs WHERE id = 1 FOR UPDATE", mode: explainAnalyze},
+		{query: "UPDATE orders SET status = 'paid' WHERE id = 1", mode: explainAnalyzeRollback},
+		{query: "WITH gone AS (DELETE FROM orders WHERE id = 1 RETURNING id) SELECT * FROM gone", mode: explainAnalyzeRollback},
+		{query: "SELECT * INTO orders_copy FROM orders", mode: explainAnalyzeRollback},
+		{query: "DELETE FROM orders", mode: explainSkip},
+		{query: "CREATE INDEX orders_user_id_idx ON orders (user_id)", mode: explainSkip},
+	}
+
+	for _, tt := range tests {
+		ast, err := parseAST(tt.query)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.query, err)
+		}
+		mode, err := explainModeFor(context.Background(), types.AnalyzeRequest{Query: tt.query}, ast)
+		if err != nil {
+			t.Fatalf("explain mode for %q: %v", tt.query, err)
+		}
+		if mode != tt.mode {
+			t.Fatalf("unexpected explain mode for %q: got %d, want %d", tt.query, mode, tt.mode)
+		}
+	}
+}
+
+func TestAnalyzeLocks(t *testing.T) {
+	cases := []struct {
+		query string
+		want  []types.LockInfo
+	}{
+		{
+			query: "SELECT j.id FROM jobs j JOIN queues q ON q.id = j.queue_id WHERE j.status = 'new' ORDER BY j.id LIMIT 10 FOR UPDATE OF j",
+			want: []types.LockInfo{
+				{Statement: "SELECT FOR UPDATE", Relation: "jobs", Mode: "RowShareLock", RowLocks: "FOR UPDATE", Warning: queueLockWarning},
+				{Statement: "SELECT FOR UPDATE", Relation: "queues", Mode: "AccessShareLock"},
+			},
+		},
+		{
+			query: "SELECT id FROM jobs WHERE status = 'new' ORDER BY id LIMIT 10 FOR UPDATE SKIP LOCKED",
+			want: []types.LockInfo{
+				{Statement: "SELECT FOR UPDATE", Relation: "jobs", Mode: "RowShareLock", RowLocks: "FOR UPDATE SKIP LOCKED"},
+			},
+		},
+		{
+			query: "UPDATE jobs SET status = 'running' WHERE id = (SELECT id FROM jobs WHERE status = 'new' ORDER BY id LIMIT 1 FOR UPDATE)",
+			want: []types.LockInfo{
+				{Statement: "UPDATE", Relation: "jobs", Mode: "RowExclusiveLock", RowLocks: "FOR UPDATE", Warning: queueLockWarning},
+			},
+		},
+		{
+			query: "WITH j AS (SELECT id FROM jobs WHERE status = 'new' LIMIT 10 FOR UPDATE SKIP LOCKED) UPDATE job_runs r SET started_at = now() FROM j WHERE r.job_id = j.id",
+			want: []types.LockInfo{
+				{Statement: "UPDATE", Relation: "job_runs", Mode: "RowExclusiveLock"},
+				{Statement: "UPDATE", Relation: "jobs", Mode: "RowShareLock", RowLocks: "FOR UPDATE SKIP LOCKED"},
+			},
+		},
+		{
+			query: "WITH j AS (SELECT id FROM jobs WHERE status = 'new' LIMIT 10 FOR UPDATE) UPDATE job_runs r SET started_at = now() FROM j WHERE r.job_id = j.id",
+			want: []types.LockInfo{
+				{Statement: "UPDATE", Relation: "job_runs", Mode: "RowExclusiveLock"},
+				{Statement: "UPDATE", Relation: "jobs", Mode: "RowShareLock", RowLocks: "FOR UPDATE", Warning: queueLockWarning},
+			},
+		},
+		{
+			query: "UPDATE orders o SET status = 'paid' FROM payments p WHERE p.order_id = o.id",
+			want: []types.LockInfo{
+				{Statement: "UPDATE", Relation: "orders", Mode: "RowExclusiveLock"},
+				{Statement: "UPDATE", Relation: "payments", Mode: "AccessShareLock"},
+			},
+		},
+		{
+			query: "ALTER TABLE orders ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID; ALTER TABLE orders VALIDATE CONSTRAINT orders_user_id_fkey; ALTER TABLE orders ADD COLUMN note text",
+			want: []types.LockInfo{
+				{Statement: "ALTER TABLE", Relation: "orders", Mode: "ShareRowExclusiveLock"},
+				{Statement: "ALTER TABLE", Relation: "users", Mode: "ShareRowExclusiveLock"},
+				{Statement: "ALTER TABLE", Relation: "orders", Mode: "ShareUpdateExclusiveLock"},
+				{Statement: "ALTER TABLE", Relation: "orders", Mode: "AccessExclusiveLock"},
+			},
+		},
+		{
+			query: "TRUNCATE audit_log; CREATE INDEX CONCURRENTLY orders_status_idx ON orders (status)",
+			want: []types.LockInfo{
+				{Statement: "TRUNCATE", Relation: "audit_log", Mode: "AccessExclusiveLock"},
+				{Statement: "CREATE INDEX CONCURRENTLY", Relation: "orders", Mode: "ShareUpdateExclusiveLock"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		ast, err := parseAST(tc.query)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.query, err)
+		}
+
+		locks := analyzeLocks(ast)
+		if !reflect.DeepEqual(locks, tc.want) {
+			t.Fatalf("%s:\n got %+v\nwant %+v", tc.query, locks, tc.want)
+		}
+	}
+}
@@ -1,17 +1,32 @@
 package rules
 
-import "strings"
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 
-func walkAST(node any, visit func(map[string]any)) {
+	pgquery "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// WalkAST calls visit for every object in the parsed query, visiting keys
+// in sorted order so results do not depend on map iteration.
+func WalkAST(node any, visit func(map[string]any)) {
 	switch typed := node.(type) {
 	case map[string]any:
 		visit(typed)
-		for _, value := range typed {
-			walkAST(value, visit)
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			WalkAST(typed[key], visit)
 		}
 	case []any:
 		for _, item := range typed {
-			walkAST(item, visit)
+			WalkAST(item, visit)
 		}
 	}
 }
@@ -63,6 +78,22 @@ func extractConstString(node any) (string, bool) {
 	return "", false
 }
 
+func extractConstInt(node any) (int64, bool) {
+	aConst, ok := ChildNode(node, "A_Const")
+	if !ok {
+		return 0, false
+	}
+
+	ival, ok := aConst["ival"].(map[string]any)
+	if !ok {
+		return 0, false
+	}
+
+	// Protobuf JSON omits zero values, so `{"ival": {}}` is the literal 0.
+	value, _ := ival["ival"].(float64)
+	return int64(value), true
+}
+
 func extractFunctionCall(node any) (funcName string, args []any, ok bool) {
 	m, ok := node.(map[string]any)
 	if !ok {
@@ -93,3 +124,249 @@ func extractFunctionCall(node any) (funcName string, args []any, ok bool) {
 	return "", nil, false
 }
 
+// ChildNode returns node[key] when node is an object holding an object there.
+func ChildNode(node any, key string) (map[string]any, bool) {
+	m, ok := node.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	child, ok := m[key].(map[string]any)
+	return child, ok
+}
+
+func extractColumnRefFields(node any) ([]string, bool) {
+	m, ok := node.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	refData, ok := m["ColumnRef"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	rawFields, ok := refData["fields"].([]any)
+	if !ok || len(rawFields) == 0 {
+		return nil, false
+	}
+
+	fields := make([]string, 0, len(rawFields))
+	for _, raw := range rawFields {
+		field, ok := raw.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if _, ok := field["A_Star"]; ok {
+			fields = append(fields, "*")
+			continue
+		}
+		strNode, ok := field["String"].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		name, _ := strNode["sval"].(string)
+		fields = append(fields, name)
+	}
+
+	return fields, true
+}
+
+func walkSelectStmts(node any, scope string, visit func(stmt map[string]any, scope string)) {
+	switch typed := node.(type) {
+	case map[string]any:
+		if stmt, ok := typed["SelectStmt"].(map[string]any); ok {
+			visit(stmt, scope)
+		}
+		if cte, ok := typed["CommonTableExpr"].(map[string]any); ok {
+			name, _ := cte["ctename"].(string)
+			scope = fmt.Sprintf("CTE %s", name)
+		}
+		if subselect, ok := typed["RangeSubselect"].(map[string]any); ok {
+			scope = "subquery"
+			if alias, ok := subselect["alias"].(map[string]any); ok {
+				if name, _ := alias["aliasname"].(string); name != "" {
+					scope = fmt.Sprintf("subquery %s", name)
+				}
+			}
+		}
+		if _, ok := typed["SubLink"]; ok {
+			scope = "subquery"
+		}
+
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkSelectStmts(typed[key], scope, visit)
+		}
+	case []any:
+		for _, item := range typed {
+			walkSelectStmts(item, scope, visit)
+		}
+	}
+}
+
+func cloneAST(ast map[string]any) (map[string]any, error) {
+	raw, err := json.Marshal(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	var clone map[string]any
+	if err := json.Unmarshal(raw, &clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+func deparseAST(ast map[string]any) (string, error) {
+	raw, err := json.Marshal(ast)
+	if err != nil {
+		return "", err
+	}
+
+	var tree pgquery.ParseResult
+	if err := protojson.Unmarshal(raw, &tree); err != nil {
+		return "", fmt.Errorf("decode AST: %w", err)
+	}
+	return pgquery.Deparse(&tree)
+}
+
+// walkPredicate is WalkAST without descending into nested queries; keys are
+// visited in sorted order for the same reason.
+func walkPredicate(node any, visit func(map[string]any)) {
+	switch typed := node.(type) {
+	case map[string]any:
+		visit(typed)
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			// Nested queries are visited as statements of their own.
+			if key != "subselect" && key != "SelectStmt" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkPredicate(typed[key], visit)
+		}
+	case []any:
+		for _, item := range typed {
+			walkPredicate(item, visit)
+		}
+	}
+}
+
+func singleRelationName(stmt map[string]any) string {
+	from, _ := stmt["fromClause"].([]any)
+	if len(from) != 1 {
+		return ""
+	}
+	rangeVar, ok := ChildNode(from[0], "RangeVar")
+	if !ok {
+		return ""
+	}
+	if alias, ok := rangeVar["alias"].(map[string]any); ok {
+		if name, _ := alias["aliasname"].(string); name != "" {
+			return name
+		}
+	}
+	name, _ := rangeVar["relname"].(string)
+	return name
+}
+
+func makeColumnRef(fields ...string) map[string]any {
+	items := make([]any, 0, len(fields))
+	for _, field := range fields {
+		items = append(items, map[string]any{"String": map[string]any{"sval": field}})
+	}
+	return map[string]any{"ColumnRef": map[string]any{"fields": items}}
+}
+
+func makeOpExpr(op string, lexpr, rexpr any) map[string]any {
+	return map[string]any{
+		"A_Expr": map[string]any{
+			"kind":  "AEXPR_OP",
+			"name":  []any{map[string]any{"String": map[string]any{"sval": op}}},
+			"lexpr": lexpr,
+			"rexpr": rexpr,
+		},
+	}
+}
+
+func makeParamRef(number int) map[string]any {
+	return map[string]any{"ParamRef": map[string]any{"number": number}}
+}
+
+func makeRowExpr(args ...any) map[string]any {
+	return map[string]any{
+		"RowExpr": map[string]any{
+			"args":       args,
+			"row_format": "COERCE_IMPLICIT_CAST",
+		},
+	}
+}
+
+func makeBoolExpr(op string, args ...any) map[string]any {
+	return map[string]any{"BoolExpr": map[string]any{"boolop": op, "args": args}}
+}
+
+func appendWhereCondition(stmt map[string]any, cond map[string]any) {
+	existing, ok := stmt["whereClause"]
+	if !ok || existing == nil {
+		stmt["whereClause"] = cond
+		return
+	}
+	stmt["whereClause"] = makeBoolExpr("AND_EXPR", existing, cond)
+}
+
+func maxParamNumber(ast any) int {
+	highest := 0
+	WalkAST(ast, func(node map[string]any) {
+		param, ok := node["ParamRef"].(map[string]any)
+		if !ok {
+			return
+		}
+		if number, _ := param["number"].(float64); int(number) > highest {
+			highest = int(number)
+		}
+	})
+	return highest
+}
+
+func exprOperator(expr map[string]any) string {
+	names, _ := expr["name"].([]any)
+	if len(names) == 0 {
+		return ""
+	}
+	last, _ := names[len(names)-1].(map[string]any)
+	str, _ := last["String"].(map[string]any)
+	name, _ := str["sval"].(string)
+	return name
+}
+
+func deparseExpr(expr any) (string, error) {
+	sql, err := deparseAST(map[string]any{
+		"stmts": []any{map[string]any{"stmt": map[string]any{"SelectStmt": map[string]any{
+			"targetList": []any{map[string]any{"ResTarget": map[string]any{
+				"val": map[string]any{"A_Const": map[string]any{"ival": map[string]any{"ival": 1}}},
+			}}},
+			"whereClause": expr,
+		}}}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(sql, "SELECT 1 WHERE "), nil
+}
+
+func hasColumnRef(node any) bool {
+	found := false
+	WalkAST(node, func(inner map[string]any) {
+		if _, ok := inner["ColumnRef"]; ok {
+			found = true
+		}
+	})
+	return found
+}
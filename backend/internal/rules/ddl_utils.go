@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"strconv"
+	"strings"
+)
+
+// lockTimeoutAdvice is appended to DDL recommendations: a waiting
+// AccessExclusiveLock blocks every query that queues behind it.
+const lockTimeoutAdvice = "Run each step with a short lock_timeout (for example SET lock_timeout = '5s') and retry, so a blocked DDL statement does not stall all traffic queued behind it."
+
+var internalTypeNames = map[string]string{
+	"int2":        "smallint",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"bool":        "boolean",
+	"bpchar":      "char",
+	"timestamptz": "timestamptz",
+}
+
+// QualifiedRelationName returns the RangeVar's relation name, prefixed with
+// its schema when one is given.
+func QualifiedRelationName(rangeVar map[string]any) string {
+	name, _ := rangeVar["relname"].(string)
+	if schema, _ := rangeVar["schemaname"].(string); schema != "" {
+		return schema + "." + name
+	}
+	return name
+}
+
+// forEachAlterTableCmd calls visit for every subcommand of every ALTER TABLE
+// statement with the name of the table it changes.
+func forEachAlterTableCmd(ast any, visit func(table string, cmd map[string]any)) {
+	WalkAST(ast, func(node map[string]any) {
+		stmt, ok := node["AlterTableStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		if objType, _ := stmt["objtype"].(string); objType != "OBJECT_TABLE" {
+			return
+		}
+		relation, _ := stmt["relation"].(map[string]any)
+		table := QualifiedRelationName(relation)
+		cmds, _ := stmt["cmds"].([]any)
+		for _, raw := range cmds {
+			if cmd, ok := ChildNode(raw, "AlterTableCmd"); ok {
+				visit(table, cmd)
+			}
+		}
+	})
+}
+
+func createdTables(ast any) map[string]struct{} {
+	tables := make(map[string]struct{})
+	WalkAST(ast, func(node map[string]any) {
+		if stmt, ok := node["CreateStmt"].(map[string]any); ok {
+			relation, _ := stmt["relation"].(map[string]any)
+			tables[QualifiedRelationName(relation)] = struct{}{}
+		}
+	})
+	return tables
+}
+
+func typeNameString(node any) string {
+	typeName, ok := node.(map[string]any)
+	if !ok {
+		return ""
+	}
+	names, _ := typeName["names"].([]any)
+	if len(names) == 0 {
+		return ""
+	}
+	last, _ := names[len(names)-1].(map[string]any)
+	str, _ := last["String"].(map[string]any)
+	name, _ := str["sval"].(string)
+	if mapped, ok := internalTypeNames[name]; ok {
+		name = mapped
+	}
+
+	mods, _ := typeName["typmods"].([]any)
+	args := make([]string, 0, len(mods))
+	for _, mod := range mods {
+		if value, ok := extractConstInt(mod); ok {
+			args = append(args, strconv.FormatInt(value, 10))
+		}
+	}
+	if len(args) > 0 {
+		name += "(" + strings.Join(args, ",") + ")"
+	}
+	return name
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+
 	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
 )
 
@@ -11,6 +13,12 @@ type Input struct {
 	AST     map[string]any
 	Plan    map[string]any
 	Request types.AnalyzeRequest
+	// DB is the connection the plan was captured on; nil in manual mode.
+	DB Querier
+}
+
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
 type Rule interface {
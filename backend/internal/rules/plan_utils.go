@@ -1,5 +1,11 @@
 package rules
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
 func extractPlanRoot(plan map[string]any) map[string]any {
 	if plan == nil {
 		return nil
@@ -42,5 +48,93 @@ func getString(node map[string]any, key string) string {
 	return ""
 }
 
+func getFloat(node map[string]any, key string) (float64, bool) {
+	if node == nil {
+		return 0, false
+	}
+	switch value := node[key].(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	}
+	return 0, false
+}
 
+func describePlanNode(node map[string]any) string {
+	nodeType := getString(node, "Node Type")
+	if nodeType == "" {
+		nodeType = "plan node"
+	}
 
+	relation := getString(node, "Relation Name")
+	if relation == "" {
+		relation = getString(node, "CTE Name")
+	}
+	if relation == "" {
+		return nodeType
+	}
+
+	if alias := getString(node, "Alias"); alias != "" && alias != relation {
+		return fmt.Sprintf("%s on %s %s", nodeType, relation, alias)
+	}
+	return fmt.Sprintf("%s on %s", nodeType, relation)
+}
+
+var planConditionKeys = []string{
+	"Index Cond",
+	"Recheck Cond",
+	"Filter",
+	"Hash Cond",
+	"Merge Cond",
+	"Join Filter",
+}
+
+func planCondition(node map[string]any) string {
+	for _, key := range planConditionKeys {
+		if cond := getString(node, key); cond != "" {
+			return cond
+		}
+	}
+	return ""
+}
+
+func formatBytes(bytes float64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	value := bytes
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%.0f %s", value, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+var filterColumnPattern = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_$]*(?:\.[A-Za-z_][A-Za-z0-9_$]*)?)\s*(?:=|<>|!=|>=|<=|>|<|!?~~\*?|IS\s|IN\s)`)
+
+func extractFilterColumns(expr string) []string {
+	columns := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, match := range filterColumnPattern.FindAllStringSubmatchIndex(expr, -1) {
+		start := match[2]
+		// Skip type names in casts such as 'paid'::text = status.
+		if start >= 2 && expr[start-2:start] == "::" {
+			continue
+		}
+		name := expr[match[2]:match[3]]
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			name = name[dot+1:]
+		}
+		if _, exists := seen[name]; exists {
+			continue
+		}
+		seen[name] = struct{}{}
+		columns = append(columns, name)
+	}
+	return columns
+}
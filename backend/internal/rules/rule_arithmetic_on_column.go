@@ -0,0 +1,182 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type ArithmeticOnColumnRule struct{}
+
+func NewArithmeticOnColumnRule() *ArithmeticOnColumnRule {
+	return &ArithmeticOnColumnRule{}
+}
+
+func (r *ArithmeticOnColumnRule) Name() string {
+	return "ArithmeticOnColumn"
+}
+
+var flippedComparisons = map[string]string{
+	"=":  "=",
+	"<>": "<>",
+	"!=": "!=",
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+}
+
+func (r *ArithmeticOnColumnRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	seen := make(map[string]struct{})
+
+	WalkAST(input.AST, func(node map[string]any) {
+		expr, ok := node["A_Expr"].(map[string]any)
+		if !ok {
+			return
+		}
+		if kind, _ := expr["kind"].(string); kind != "AEXPR_OP" {
+			return
+		}
+		op := exprOperator(expr)
+		if _, ok := flippedComparisons[op]; !ok {
+			return
+		}
+
+		arithmetic, other := expr["lexpr"], expr["rexpr"]
+		column, arithOp := arithmeticColumn(arithmetic)
+		if column == "" {
+			arithmetic, other = other, arithmetic
+			op = flippedComparisons[op]
+			if column, arithOp = arithmeticColumn(arithmetic); column == "" {
+				return
+			}
+		}
+
+		key := column + ":" + arithOp
+		if _, exists := seen[key]; exists {
+			return
+		}
+		seen[key] = struct{}{}
+
+		recommendation := fmt.Sprintf("Keep %s alone on one side of the comparison, or create an expression index matching the expression if it cannot be rewritten.", column)
+		if rewritten, ok := isolateColumn(arithmetic, op, other); ok {
+			if predicate, err := deparseExpr(rewritten); err == nil {
+				recommendation = fmt.Sprintf("Move the arithmetic to the constant side so an index on %s can be used: %s.", column, predicate)
+			}
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Arithmetic applied to column in predicate",
+			Description:    fmt.Sprintf("Column %s is used with the %s operator inside a comparison, so the expression is computed for every row and an index on %s is not used.", column, arithOp, column),
+			Recommendation: recommendation,
+			Severity:       types.SeverityMedium,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func arithmeticColumn(node any) (string, string) {
+	expr, ok := ChildNode(node, "A_Expr")
+	if !ok {
+		return "", ""
+	}
+	if kind, _ := expr["kind"].(string); kind != "AEXPR_OP" {
+		return "", ""
+	}
+	op := exprOperator(expr)
+	if !strings.Contains("+-*/", op) || len(op) != 1 {
+		return "", ""
+	}
+
+	for _, key := range []string{"lexpr", "rexpr"} {
+		if fields, ok := extractColumnRefFields(expr[key]); ok {
+			return strings.Join(fields, "."), op
+		}
+	}
+	return "", ""
+}
+
+// isolateColumn solves `column <arithOp> k <op> value` for the column when
+// the arithmetic is linear in it and returns the equivalent predicate.
+func isolateColumn(arithmetic any, op string, value any) (any, bool) {
+	expr, _ := ChildNode(arithmetic, "A_Expr")
+	arithOp := exprOperator(expr)
+	left, right := expr["lexpr"], expr["rexpr"]
+
+	column, constant, columnLeft := left, right, true
+	if _, ok := extractColumnRefFields(left); !ok {
+		column, constant, columnLeft = right, left, false
+	}
+	if constant == nil || hasColumnRef(constant) {
+		return nil, false
+	}
+
+	switch arithOp {
+	case "+":
+		return makeOpExpr(op, column, makeOpExpr("-", value, constant)), true
+	case "-":
+		if columnLeft {
+			return makeOpExpr(op, column, makeOpExpr("+", value, constant)), true
+		}
+		return makeOpExpr(flippedComparisons[op], column, makeOpExpr("-", constant, value)), true
+	case "*", "/":
+		if arithOp == "/" && !columnLeft {
+			return nil, false
+		}
+		sign, ok := constantSign(constant)
+		if !ok {
+			return nil, false
+		}
+		// Integer division truncates, so dividing both sides is not exact.
+		if arithOp == "/" && sign.integer {
+			return nil, false
+		}
+		if sign.negative {
+			op = flippedComparisons[op]
+		}
+		if arithOp == "*" {
+			if sign.integer {
+				constant = map[string]any{"TypeCast": map[string]any{
+					"arg": constant,
+					"typeName": map[string]any{"names": []any{
+						map[string]any{"String": map[string]any{"sval": "pg_catalog"}},
+						map[string]any{"String": map[string]any{"sval": "numeric"}},
+					}},
+				}}
+			}
+			return makeOpExpr(op, column, makeOpExpr("/", value, constant)), true
+		}
+		return makeOpExpr(op, column, makeOpExpr("*", value, constant)), true
+	}
+	return nil, false
+}
+
+type numericSign struct {
+	negative bool
+	integer  bool
+}
+
+func constantSign(node any) (numericSign, bool) {
+	if value, ok := extractConstInt(node); ok {
+		// Zero cannot be divided by, and negative literals may come back
+		// from the parser as zero, so the sign is unknown.
+		if value == 0 {
+			return numericSign{}, false
+		}
+		return numericSign{negative: value < 0, integer: true}, true
+	}
+	aConst, ok := ChildNode(node, "A_Const")
+	if !ok {
+		return numericSign{}, false
+	}
+	fval, _ := aConst["fval"].(map[string]any)
+	raw, _ := fval["fval"].(string)
+	if raw == "" || strings.Trim(raw, "-+0.") == "" {
+		return numericSign{}, false
+	}
+	return numericSign{negative: strings.HasPrefix(raw, "-")}, true
+}
@@ -0,0 +1,169 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	blockSize                  = 8192
+	defaultBufferMinReadBlocks = 1000
+	defaultBufferMinTempBlocks = 128
+	defaultBufferMinHitRatio   = 0.9
+)
+
+type BufferUsageRule struct {
+	MinReadBlocks float64
+	MinTempBlocks float64
+	MinHitRatio   float64
+}
+
+func NewBufferUsageRule() *BufferUsageRule {
+	return &BufferUsageRule{
+		MinReadBlocks: defaultBufferMinReadBlocks,
+		MinTempBlocks: defaultBufferMinTempBlocks,
+		MinHitRatio:   defaultBufferMinHitRatio,
+	}
+}
+
+func (r *BufferUsageRule) Name() string {
+	return "BufferUsage"
+}
+
+type bufferCounts struct {
+	hit       float64
+	read      float64
+	tempRead  float64
+	tempWrite float64
+}
+
+func (c bufferCounts) hitRatio() float64 {
+	if c.hit+c.read == 0 {
+		return 1
+	}
+	return c.hit / (c.hit + c.read)
+}
+
+func (c bufferCounts) temp() float64 {
+	return c.tempRead + c.tempWrite
+}
+
+func (r *BufferUsageRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	root := extractPlanRoot(input.Plan)
+	if root == nil {
+		return nil, nil
+	}
+	if _, ok := root["Shared Hit Blocks"]; !ok {
+		// EXPLAIN was run without BUFFERS.
+		return nil, nil
+	}
+
+	suggestions := make([]types.Suggestion, 0)
+	total := readBufferCounts(root)
+	planning, _ := input.Plan["Planning"].(map[string]any)
+	planningCounts := readBufferCounts(planning)
+
+	if total.read >= r.MinReadBlocks || total.temp() >= r.MinTempBlocks {
+		severity := types.SeverityLow
+		if total.hitRatio() < r.MinHitRatio && total.read >= r.MinReadBlocks {
+			severity = types.SeverityMedium
+		}
+
+		description := fmt.Sprintf("The query touched %s of shared buffers with a %.1f%% cache hit ratio: %s from cache, %s read from disk or the OS cache",
+			formatBytes((total.hit+total.read)*blockSize), total.hitRatio()*100, formatBytes(total.hit*blockSize), formatBytes(total.read*blockSize))
+		if total.temp() > 0 {
+			description += fmt.Sprintf(", plus %s of temporary file I/O", formatBytes(total.temp()*blockSize))
+		}
+		description += "."
+		if planningCounts.read > 0 {
+			description += fmt.Sprintf(" Planning alone read %s of catalog data, a sign of a cold session or catalog cache.", formatBytes(planningCounts.read*blockSize))
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Buffer and cache usage",
+			Description:    description,
+			Recommendation: "Run the query again: if the reads turn into hits, this run hit a cold cache. If the total volume stays high, the plan touches too much data; look at the nodes with the largest reads below.",
+			Severity:       severity,
+		})
+	}
+
+	traversePlan(root, func(node map[string]any) {
+		own := exclusiveBufferCounts(node)
+
+		if own.read >= r.MinReadBlocks {
+			suggestions = append(suggestions, types.Suggestion{
+				Title: "Heavy disk reads in plan node",
+				Description: fmt.Sprintf("%s read %s outside shared buffers itself (hit ratio %.1f%%).",
+					describePlanNode(node), formatBytes(own.read*blockSize), own.hitRatio()*100),
+				Recommendation: "Reduce the pages this node visits with a more selective index or a covering index, or make sure its table fits in shared_buffers if it is read often.",
+				Severity:       types.SeverityMedium,
+			})
+		}
+
+		// DiskSpillRule already reports spilled sorts and hashes, including
+		// the Hash Join reading back the batches of its spilled Hash.
+		if own.temp() >= r.MinTempBlocks && !spillReported(node) {
+			suggestions = append(suggestions, types.Suggestion{
+				Title: "Temporary file I/O in plan node",
+				Description: fmt.Sprintf("%s wrote %s and read %s of temporary files.",
+					describePlanNode(node), formatBytes(own.tempWrite*blockSize), formatBytes(own.tempRead*blockSize)),
+				Recommendation: "The node ran out of work_mem and spilled to temporary files. Raise work_mem for this query or reduce the rows it has to sort, hash or materialize.",
+				Severity:       types.SeverityMedium,
+			})
+		}
+	})
+
+	return suggestions, nil
+}
+
+func readBufferCounts(node map[string]any) bufferCounts {
+	var counts bufferCounts
+	counts.hit, _ = getFloat(node, "Shared Hit Blocks")
+	counts.read, _ = getFloat(node, "Shared Read Blocks")
+	counts.tempRead, _ = getFloat(node, "Temp Read Blocks")
+	counts.tempWrite, _ = getFloat(node, "Temp Written Blocks")
+	return counts
+}
+
+// EXPLAIN reports buffers cumulatively, so a node's own I/O is its total
+// minus that of its children.
+func exclusiveBufferCounts(node map[string]any) bufferCounts {
+	own := readBufferCounts(node)
+	children, _ := node["Plans"].([]any)
+	for _, child := range children {
+		childNode, ok := child.(map[string]any)
+		if !ok {
+			continue
+		}
+		counts := readBufferCounts(childNode)
+		own.hit -= counts.hit
+		own.read -= counts.read
+		own.tempRead -= counts.tempRead
+		own.tempWrite -= counts.tempWrite
+	}
+	own.hit = max(own.hit, 0)
+	own.read = max(own.read, 0)
+	own.tempRead = max(own.tempRead, 0)
+	own.tempWrite = max(own.tempWrite, 0)
+	return own
+}
+
+func spillReported(node map[string]any) bool {
+	if _, _, ok := detectDiskSpill(node); ok {
+		return true
+	}
+	if getString(node, "Node Type") != "Hash Join" {
+		return false
+	}
+	children, _ := node["Plans"].([]any)
+	for _, child := range children {
+		if hash, ok := child.(map[string]any); ok && getString(hash, "Node Type") == "Hash" {
+			if _, _, ok := detectDiskSpill(hash); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
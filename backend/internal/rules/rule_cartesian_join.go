@@ -0,0 +1,300 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type CartesianJoinRule struct{}
+
+func NewCartesianJoinRule() *CartesianJoinRule {
+	return &CartesianJoinRule{}
+}
+
+func (r *CartesianJoinRule) Name() string {
+	return "CartesianJoin"
+}
+
+func (r *CartesianJoinRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	root := extractPlanRoot(input.Plan)
+	seen := make(map[string]struct{})
+
+	report := func(left, right []string, explicit bool) {
+		if len(left) == 0 || len(right) == 0 {
+			return
+		}
+		key := left[0] + ":" + right[0]
+		if _, exists := seen[key]; exists {
+			return
+		}
+		seen[key] = struct{}{}
+
+		description := fmt.Sprintf("%s and %s are joined without any join condition, so every row of one is paired with every row of the other.", left[0], right[0])
+		if explicit {
+			description = fmt.Sprintf("%s is CROSS JOINed with %s without any join condition, so every row of one is paired with every row of the other.", left[0], right[0])
+		}
+
+		severity := types.SeverityMedium
+		if rows, ok := confirmCartesianInPlan(root, left, right); ok {
+			severity = types.SeverityHigh
+			description += fmt.Sprintf(" The plan confirms it: a Nested Loop without a join filter or index condition produced %.0f rows.", rows)
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Cartesian product between relations",
+			Description:    description,
+			Recommendation: fmt.Sprintf("Add the missing join condition between %s and %s (JOIN ... ON or a WHERE predicate). Keep the cross join only if the full product is really intended.", left[0], right[0]),
+			Severity:       severity,
+		})
+	}
+
+	WalkAST(input.AST, func(node map[string]any) {
+		stmt, ok := node["SelectStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		if groups, explicit, ok := unjoinedRelations(stmt); ok {
+			report(groups[0], groups[1], explicit)
+		}
+	})
+
+	return suggestions, nil
+}
+
+func isUnqualifiedJoin(join map[string]any) bool {
+	if joinType, _ := join["jointype"].(string); joinType != "JOIN_INNER" {
+		return false
+	}
+	if natural, _ := join["isNatural"].(bool); natural {
+		return false
+	}
+	if _, ok := join["usingClause"]; ok {
+		return false
+	}
+	_, ok := join["quals"]
+	return !ok
+}
+
+func isLateral(node any) bool {
+	for _, key := range []string{"RangeSubselect", "RangeFunction"} {
+		if item, ok := ChildNode(node, key); ok {
+			lateral, _ := item["lateral"].(bool)
+			return lateral
+		}
+	}
+	return false
+}
+
+func fromItemNames(node any) []string {
+	names := make([]string, 0)
+	if rangeVar, ok := ChildNode(node, "RangeVar"); ok {
+		name, _ := rangeVar["relname"].(string)
+		if alias, ok := rangeVar["alias"].(map[string]any); ok {
+			if aliasName, _ := alias["aliasname"].(string); aliasName != "" {
+				name = aliasName
+			}
+		}
+		return append(names, name)
+	}
+	if join, ok := ChildNode(node, "JoinExpr"); ok {
+		names = append(names, fromItemNames(join["larg"])...)
+		return append(names, fromItemNames(join["rarg"])...)
+	}
+	for _, key := range []string{"RangeSubselect", "RangeFunction"} {
+		if item, ok := ChildNode(node, key); ok {
+			if alias, ok := item["alias"].(map[string]any); ok {
+				if name, _ := alias["aliasname"].(string); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// unjoinedRelations groups the relations in FROM by the join conditions and
+// WHERE predicates linking them and returns two groups that nothing links.
+// explicit reports whether the statement spells out a CROSS JOIN.
+func unjoinedRelations(stmt map[string]any) ([][]string, bool, bool) {
+	from, _ := stmt["fromClause"].([]any)
+	names := make([]string, 0)
+	for _, item := range from {
+		names = append(names, fromItemNames(item)...)
+	}
+	if len(names) < 2 {
+		return nil, false, false
+	}
+
+	parent := make(map[string]string, len(names))
+	for _, name := range names {
+		parent[name] = name
+	}
+	var find func(string) string
+	find = func(name string) string {
+		for parent[name] != name {
+			name = parent[name]
+		}
+		return name
+	}
+	union := func(group []string) {
+		known := make([]string, 0, len(group))
+		for _, name := range group {
+			if _, ok := parent[name]; ok {
+				known = append(known, name)
+			}
+		}
+		for _, name := range known[min(1, len(known)):] {
+			parent[find(name)] = find(known[0])
+		}
+	}
+
+	lateral, explicit := false, false
+	walkPredicate(from, func(node map[string]any) {
+		if isLateral(node) {
+			lateral = true
+		}
+		join, ok := node["JoinExpr"].(map[string]any)
+		if !ok {
+			return
+		}
+		if isUnqualifiedJoin(join) {
+			explicit = true
+			return
+		}
+		sides := append(fromItemNames(join["larg"]), fromItemNames(join["rarg"])...)
+		if quals, ok := join["quals"]; ok {
+			linkRelations(quals, parent, sides, union)
+			return
+		}
+		union(sides)
+	})
+	if lateral {
+		return nil, false, false
+	}
+
+	linkRelations(stmt["whereClause"], parent, names, union)
+
+	groups := make(map[string][]string)
+	order := make([]string, 0)
+	for _, name := range names {
+		root := find(name)
+		if _, ok := groups[root]; !ok {
+			order = append(order, root)
+		}
+		groups[root] = append(groups[root], name)
+	}
+	if len(order) < 2 {
+		return nil, false, false
+	}
+	return [][]string{groups[order[0]], groups[order[1]]}, explicit, true
+}
+
+// linkRelations calls link with the relations each conjunct of expr
+// references, whatever kind of expression it is: comparisons, function
+// calls such as st_intersects(a.g, b.g), OR trees, NULL tests and
+// correlated subqueries. A conjunct with unqualified columns could involve
+// any of them, so it links the whole fallback group.
+func linkRelations(expr any, known map[string]string, fallback []string, link func([]string)) {
+	for _, conjunct := range andConjuncts(expr) {
+		qualifiers := make([]string, 0)
+		columns, unqualified := 0, false
+		addColumn := func(fields []string, correlated bool) {
+			if len(fields) < 2 {
+				// Unqualified columns inside a subquery belong to it.
+				if !correlated {
+					columns++
+					unqualified = true
+				}
+				return
+			}
+			if _, ok := known[fields[len(fields)-2]]; ok {
+				columns++
+				qualifiers = append(qualifiers, fields[len(fields)-2])
+			}
+		}
+		walkPredicate(conjunct, func(node map[string]any) {
+			if fields, ok := extractColumnRefFields(node); ok {
+				addColumn(fields, false)
+			}
+			if subLink, ok := node["SubLink"].(map[string]any); ok {
+				WalkAST(subLink["subselect"], func(inner map[string]any) {
+					if fields, ok := extractColumnRefFields(inner); ok {
+						addColumn(fields, true)
+					}
+				})
+			}
+		})
+		if columns < 2 {
+			continue
+		}
+		if unqualified {
+			link(fallback)
+			continue
+		}
+		link(qualifiers)
+	}
+}
+
+// andConjuncts splits expr into the arguments of its top-level ANDs.
+func andConjuncts(expr any) []any {
+	if boolExpr, ok := ChildNode(expr, "BoolExpr"); ok {
+		if boolop, _ := boolExpr["boolop"].(string); boolop == "AND_EXPR" {
+			conjuncts := make([]any, 0)
+			args, _ := boolExpr["args"].([]any)
+			for _, arg := range args {
+				conjuncts = append(conjuncts, andConjuncts(arg)...)
+			}
+			return conjuncts
+		}
+	}
+	if expr == nil {
+		return nil
+	}
+	return []any{expr}
+}
+
+func confirmCartesianInPlan(root map[string]any, left, right []string) (float64, bool) {
+	var rows float64
+	found := false
+	traversePlan(root, func(node map[string]any) {
+		if found || getString(node, "Node Type") != "Nested Loop" || getString(node, "Join Filter") != "" {
+			return
+		}
+
+		outer, inner := joinChildren(node)
+		if outer == nil || inner == nil {
+			return
+		}
+		conditioned := false
+		traversePlan(inner, func(child map[string]any) {
+			if getString(child, "Index Cond") != "" || getString(child, "Recheck Cond") != "" || getString(child, "Hash Cond") != "" || getString(child, "Merge Cond") != "" {
+				conditioned = true
+			}
+		})
+		if conditioned {
+			return
+		}
+
+		if planSubtreeMentions(outer, left) && planSubtreeMentions(inner, right) ||
+			planSubtreeMentions(outer, right) && planSubtreeMentions(inner, left) {
+			rows = planNodeRows(node)
+			found = true
+		}
+	})
+	return rows, found
+}
+
+func planSubtreeMentions(node map[string]any, names []string) bool {
+	found := false
+	traversePlan(node, func(child map[string]any) {
+		for _, name := range names {
+			if getString(child, "Alias") == name || getString(child, "Relation Name") == name {
+				found = true
+			}
+		}
+	})
+	return found
+}
@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type ColumnCastRule struct{}
+
+func NewColumnCastRule() *ColumnCastRule {
+	return &ColumnCastRule{}
+}
+
+func (r *ColumnCastRule) Name() string {
+	return "ColumnCast"
+}
+
+// relabelCastTypes are the casts PostgreSQL adds to varchar, char and citext
+// columns when comparing them as text. They are binary-coercible relabels,
+// not a cast the user wrote, and do not prevent index use.
+var relabelCastTypes = map[string]struct{}{
+	"text":              {},
+	"character varying": {},
+	"varchar":           {},
+	"bpchar":            {},
+}
+
+var filterCastPattern = regexp.MustCompile(`\(([A-Za-z_][A-Za-z0-9_$]*(?:\.[A-Za-z_][A-Za-z0-9_$]*)?)\)::([A-Za-z_][A-Za-z0-9_ ]*?)\s*(?:[=<>!~)]|$|\sIS\s)`)
+
+func (r *ColumnCastRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	seen := make(map[string]struct{})
+
+	WalkAST(input.AST, func(node map[string]any) {
+		expr, ok := node["A_Expr"].(map[string]any)
+		if !ok {
+			return
+		}
+
+		checkOperand := func(operand, other any) {
+			column, typeName, ok := extractColumnCast(operand)
+			if !ok {
+				return
+			}
+			key := column[strings.LastIndex(column, ".")+1:]
+			if _, exists := seen[key]; exists {
+				return
+			}
+			seen[key] = struct{}{}
+
+			suggestions = append(suggestions, types.Suggestion{
+				Title:          "Cast applied to column in predicate",
+				Description:    fmt.Sprintf("Column %s is cast to %s in a predicate, so a plain index on %s cannot be used.", column, typeName, column),
+				Recommendation: columnCastRecommendation(column, typeName, exprOperator(expr), other),
+				Severity:       types.SeverityMedium,
+			})
+		}
+
+		checkOperand(expr["lexpr"], expr["rexpr"])
+		checkOperand(expr["rexpr"], expr["lexpr"])
+	})
+
+	traversePlan(extractPlanRoot(input.Plan), func(node map[string]any) {
+		filter := getString(node, "Filter")
+		for _, match := range filterCastPattern.FindAllStringSubmatch(filter, -1) {
+			column := match[1]
+			typeName := strings.TrimSpace(match[2])
+			if _, relabel := relabelCastTypes[typeName]; relabel {
+				continue
+			}
+			short := column[strings.LastIndex(column, ".")+1:]
+			if _, exists := seen[short]; exists {
+				continue
+			}
+			seen[short] = struct{}{}
+
+			// The query text may not show the cast: PostgreSQL adds it when
+			// the compared value has a different type than the column.
+			suggestions = append(suggestions, types.Suggestion{
+				Title:          "Cast applied to column in filter",
+				Description:    fmt.Sprintf("The %s filters on (%s)::%s, so the cast is evaluated for every row and an index on %s is not used.", describePlanNode(node), column, typeName, short),
+				Recommendation: fmt.Sprintf("Compare %s with a value of its own type (cast the parameter or literal, not the column), or create an expression index on ((%s::%s)) if the cast is intended.", short, short, typeName),
+				Severity:       types.SeverityLow,
+			})
+		}
+	})
+
+	return suggestions, nil
+}
+
+func extractColumnCast(node any) (string, string, bool) {
+	typeCast, ok := ChildNode(node, "TypeCast")
+	if !ok {
+		return "", "", false
+	}
+	fields, ok := extractColumnRefFields(typeCast["arg"])
+	if !ok || fields[len(fields)-1] == "*" {
+		return "", "", false
+	}
+
+	typeNameNode, _ := typeCast["typeName"].(map[string]any)
+	names, _ := typeNameNode["names"].([]any)
+	if len(names) == 0 {
+		return "", "", false
+	}
+	last, _ := names[len(names)-1].(map[string]any)
+	str, _ := last["String"].(map[string]any)
+	typeName, _ := str["sval"].(string)
+
+	return strings.Join(fields, "."), typeName, true
+}
+
+func columnCastRecommendation(column, typeName, op string, other any) string {
+	value, isConst := extractConstString(other)
+	if inner, ok := ChildNode(other, "TypeCast"); ok {
+		value, isConst = extractConstString(inner["arg"])
+	}
+
+	if typeName == "date" && isConst && op == "=" {
+		return fmt.Sprintf("Use a range predicate on the raw column instead: %s >= '%s' AND %s < '%s'::date + 1.", column, value, column, value)
+	}
+	return fmt.Sprintf("Cast the other side of the comparison to the type of %s instead of casting the column, or create an expression index on ((%s::%s)) if the cast is intended.", column, column, typeName)
+}
@@ -0,0 +1,54 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type ColumnTypeChangeRule struct{}
+
+func NewColumnTypeChangeRule() *ColumnTypeChangeRule {
+	return &ColumnTypeChangeRule{}
+}
+
+func (r *ColumnTypeChangeRule) Name() string {
+	return "ColumnTypeChange"
+}
+
+func (r *ColumnTypeChangeRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+
+	forEachAlterTableCmd(input.AST, func(table string, cmd map[string]any) {
+		if subtype, _ := cmd["subtype"].(string); subtype != "AT_AlterColumnType" {
+			return
+		}
+		column, _ := cmd["name"].(string)
+		columnDef, _ := ChildNode(cmd["def"], "ColumnDef")
+		newType := typeNameString(columnDef["typeName"])
+		_, hasUsing := columnDef["raw_default"]
+
+		description := fmt.Sprintf("ALTER COLUMN %s TYPE %s on %s takes an AccessExclusiveLock, blocking reads and writes.", column, newType, table)
+		severity := types.SeverityHigh
+		// Widening to text or varchar is binary-compatible with the usual
+		// source types and does not rewrite the table.
+		if base := strings.SplitN(newType, "(", 2)[0]; !hasUsing && (base == "text" || base == "varchar") {
+			description += " Changing to " + newType + " avoids a rewrite only if the old type is binary-compatible (such as varchar to text or a longer varchar); otherwise the table and its indexes are rewritten."
+			severity = types.SeverityMedium
+		} else {
+			description += " The table and all of its indexes are rewritten while the lock is held."
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:       "Column type change rewrites the table",
+			Description: description,
+			Recommendation: fmt.Sprintf("Add a new column of type %s, keep it in sync with a trigger or dual writes, backfill it in batches, build its indexes CONCURRENTLY, then swap the columns in a short transaction. %s",
+				newType, lockTimeoutAdvice),
+			Severity: severity,
+		})
+	})
+
+	return suggestions, nil
+}
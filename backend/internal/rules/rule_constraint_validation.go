@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type ConstraintValidationRule struct{}
+
+func NewConstraintValidationRule() *ConstraintValidationRule {
+	return &ConstraintValidationRule{}
+}
+
+func (r *ConstraintValidationRule) Name() string {
+	return "ConstraintValidation"
+}
+
+func (r *ConstraintValidationRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+
+	forEachAlterTableCmd(input.AST, func(table string, cmd map[string]any) {
+		if subtype, _ := cmd["subtype"].(string); subtype != "AT_AddConstraint" {
+			return
+		}
+		constraint, ok := ChildNode(cmd["def"], "Constraint")
+		if !ok {
+			return
+		}
+		name, _ := constraint["conname"].(string)
+		if name == "" {
+			name = "the constraint"
+		}
+
+		switch contype, _ := constraint["contype"].(string); contype {
+		case "CONSTR_FOREIGN", "CONSTR_CHECK":
+			if skip, _ := constraint["skip_validation"].(bool); skip {
+				return
+			}
+			description := fmt.Sprintf("ADD CONSTRAINT %s CHECK on %s takes an AccessExclusiveLock, blocking reads and writes, while it validates every existing row.", name, table)
+			if contype == "CONSTR_FOREIGN" {
+				referenced, _ := constraint["pktable"].(map[string]any)
+				description = fmt.Sprintf("ADD CONSTRAINT %s FOREIGN KEY on %s takes a ShareRowExclusiveLock on both %s and %s, blocking writes to them, while it validates every existing row.",
+					name, table, table, QualifiedRelationName(referenced))
+			}
+			suggestions = append(suggestions, types.Suggestion{
+				Title:       "Constraint added without NOT VALID",
+				Description: description,
+				Recommendation: fmt.Sprintf("Add it with NOT VALID, which only checks new rows and commits immediately, then run ALTER TABLE %s VALIDATE CONSTRAINT %s in a separate transaction; validation takes a ShareUpdateExclusiveLock and does not block reads or writes. %s",
+					table, name, lockTimeoutAdvice),
+				Severity: types.SeverityHigh,
+			})
+		case "CONSTR_PRIMARY", "CONSTR_UNIQUE":
+			if index, _ := constraint["indexname"].(string); index != "" {
+				return
+			}
+			kind := "PRIMARY KEY"
+			if contype == "CONSTR_UNIQUE" {
+				kind = "UNIQUE"
+			}
+			suggestions = append(suggestions, types.Suggestion{
+				Title:       "Constraint builds its index under an exclusive lock",
+				Description: fmt.Sprintf("ADD CONSTRAINT %s %s on %s builds a unique index while holding an AccessExclusiveLock, blocking reads and writes for the whole build.", name, kind, table),
+				Recommendation: fmt.Sprintf("Build the index first with CREATE UNIQUE INDEX CONCURRENTLY, then attach it with ALTER TABLE %s ADD CONSTRAINT %s %s USING INDEX, which only needs a brief lock. %s",
+					table, name, kind, lockTimeoutAdvice),
+				Severity: types.SeverityHigh,
+			})
+		}
+	})
+
+	return suggestions, nil
+}
@@ -0,0 +1,212 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultSubPlanMinLoops     = 100
+	defaultSubPlanMinTotalTime = 10
+	subPlanHighTotalTime       = 1000
+)
+
+type CorrelatedSubPlanRule struct {
+	MinLoops     float64
+	MinTotalTime float64
+}
+
+func NewCorrelatedSubPlanRule() *CorrelatedSubPlanRule {
+	return &CorrelatedSubPlanRule{
+		MinLoops:     defaultSubPlanMinLoops,
+		MinTotalTime: defaultSubPlanMinTotalTime,
+	}
+}
+
+func (r *CorrelatedSubPlanRule) Name() string {
+	return "CorrelatedSubPlan"
+}
+
+type correlatedSubLink struct {
+	linkType  string
+	relations []string
+	outerRefs []string
+	inTargets bool
+}
+
+func (r *CorrelatedSubPlanRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	root := extractPlanRoot(input.Plan)
+	if root == nil {
+		return nil, nil
+	}
+
+	executionTime, _ := getFloat(input.Plan, "Execution Time")
+	subLinks := collectCorrelatedSubLinks(input.AST)
+
+	suggestions := make([]types.Suggestion, 0)
+	traversePlan(root, func(node map[string]any) {
+		if getString(node, "Parent Relationship") != "SubPlan" {
+			return
+		}
+		loops, _ := getFloat(node, "Actual Loops")
+		loopTime, _ := getFloat(node, "Actual Total Time")
+		totalTime := loopTime * loops
+		if loops < r.MinLoops || totalTime < r.MinTotalTime {
+			return
+		}
+
+		name := getString(node, "Subplan Name")
+		if name == "" {
+			name = "SubPlan"
+		}
+		description := fmt.Sprintf("%s (%s) ran %.0f times at %.3f ms per loop, %.1f ms in total", name, describePlanNode(node), loops, loopTime, totalTime)
+		if executionTime > 0 {
+			description += fmt.Sprintf(" (%.0f%% of execution time)", totalTime/executionTime*100)
+		}
+		description += "."
+
+		recommendation := "Rewrite the correlated subquery as a JOIN (or LEFT JOIN LATERAL) so it is evaluated once for the whole set instead of once per outer row."
+		if link := matchSubLink(subLinks, node); link != nil {
+			description += fmt.Sprintf(" It comes from a subquery on %s correlated through %s.", strings.Join(link.relations, ", "), strings.Join(link.outerRefs, ", "))
+			recommendation = subPlanRecommendation(link)
+		}
+		// A set-based rewrite runs the subquery work roughly once.
+		recommendation += fmt.Sprintf(" Estimated saving: up to %.1f ms.", totalTime-loopTime)
+
+		severity := types.SeverityMedium
+		if totalTime >= subPlanHighTotalTime || (executionTime > 0 && totalTime >= executionTime/2) {
+			severity = types.SeverityHigh
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Correlated subquery executed per row",
+			Description:    description,
+			Recommendation: recommendation,
+			Severity:       severity,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func collectCorrelatedSubLinks(ast map[string]any) []*correlatedSubLink {
+	links := make([]*correlatedSubLink, 0)
+
+	addLink := func(subLink map[string]any, inTargets bool) {
+		subselect, ok := subLink["subselect"]
+		if !ok {
+			return
+		}
+
+		ownNames := make(map[string]struct{})
+		relations := make([]string, 0)
+		WalkAST(subselect, func(node map[string]any) {
+			if rangeVar, ok := node["RangeVar"].(map[string]any); ok {
+				relname, _ := rangeVar["relname"].(string)
+				if !slices.Contains(relations, relname) {
+					relations = append(relations, relname)
+				}
+				ownNames[relname] = struct{}{}
+			}
+			for _, name := range fromItemNames(node) {
+				ownNames[name] = struct{}{}
+			}
+		})
+
+		outerRefs := make([]string, 0)
+		WalkAST(subselect, func(node map[string]any) {
+			fields, ok := extractColumnRefFields(node)
+			if !ok || len(fields) < 2 {
+				return
+			}
+			if _, own := ownNames[fields[len(fields)-2]]; own {
+				return
+			}
+			ref := strings.Join(fields, ".")
+			if !slices.Contains(outerRefs, ref) {
+				outerRefs = append(outerRefs, ref)
+			}
+		})
+		if len(outerRefs) == 0 {
+			return
+		}
+
+		linkType, _ := subLink["subLinkType"].(string)
+		links = append(links, &correlatedSubLink{
+			linkType:  linkType,
+			relations: relations,
+			outerRefs: outerRefs,
+			inTargets: inTargets,
+		})
+	}
+
+	WalkAST(ast, func(node map[string]any) {
+		stmt, ok := node["SelectStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		// Sorted keys keep the link order, which matchSubLink tie-breaks on,
+		// stable across runs.
+		keys := make([]string, 0, len(stmt))
+		for key := range stmt {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			inTargets := key == "targetList"
+			walkPredicate(stmt[key], func(inner map[string]any) {
+				if subLink, ok := inner["SubLink"].(map[string]any); ok {
+					addLink(subLink, inTargets)
+				}
+			})
+		}
+	})
+
+	return links
+}
+
+func matchSubLink(links []*correlatedSubLink, node map[string]any) *correlatedSubLink {
+	relations := make([]string, 0)
+	traversePlan(node, func(child map[string]any) {
+		if relation := getString(child, "Relation Name"); relation != "" {
+			relations = append(relations, relation)
+		}
+	})
+
+	var best *correlatedSubLink
+	bestScore := 0
+	for _, link := range links {
+		score := 0
+		for _, relation := range link.relations {
+			if slices.Contains(relations, relation) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = link, score
+		}
+	}
+	if best == nil && len(links) == 1 {
+		best = links[0]
+	}
+	return best
+}
+
+func subPlanRecommendation(link *correlatedSubLink) string {
+	relations := strings.Join(link.relations, ", ")
+	switch {
+	case link.inTargets:
+		return fmt.Sprintf("Move the scalar subquery on %s out of the SELECT list: aggregate it once with GROUP BY on the correlation columns and LEFT JOIN the result, or use LEFT JOIN LATERAL.", relations)
+	case link.linkType == "EXISTS_SUBLINK":
+		return fmt.Sprintf("The EXISTS subquery on %s could not be turned into a semi-join, usually because it sits under OR or references outer columns in a non-equality. Restructure it as a plain JOIN or split the OR into UNION ALL branches.", relations)
+	case link.linkType == "ANY_SUBLINK":
+		return fmt.Sprintf("Rewrite the correlated IN subquery on %s as EXISTS or a JOIN on the correlation columns so it can be planned as a semi-join.", relations)
+	default:
+		return fmt.Sprintf("Rewrite the correlated subquery on %s as a JOIN or LEFT JOIN LATERAL so it is evaluated once for the whole set instead of once per outer row.", relations)
+	}
+}
@@ -0,0 +1,169 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type CTEMaterializationRule struct{}
+
+func NewCTEMaterializationRule() *CTEMaterializationRule {
+	return &CTEMaterializationRule{}
+}
+
+func (r *CTEMaterializationRule) Name() string {
+	return "CTEMaterialization"
+}
+
+type cteDefinition struct {
+	name         string
+	materialized string
+	recursive    bool
+	references   int
+}
+
+func (r *CTEMaterializationRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	root := extractPlanRoot(input.Plan)
+
+	for _, cte := range collectCTEs(input.AST) {
+		scans := make([]map[string]any, 0)
+		traversePlan(root, func(node map[string]any) {
+			if getString(node, "Node Type") == "CTE Scan" && getString(node, "CTE Name") == cte.name {
+				scans = append(scans, node)
+			}
+		})
+
+		if len(scans) == 0 {
+			if root != nil && cte.materialized == "CTEMaterializeNever" && cte.references > 1 {
+				suggestions = append(suggestions, types.Suggestion{
+					Title:          "NOT MATERIALIZED CTE evaluated repeatedly",
+					Description:    fmt.Sprintf("CTE %s is declared NOT MATERIALIZED and referenced %d times, so its query is inlined and executed once per reference.", cte.name, cte.references),
+					Recommendation: fmt.Sprintf("Declare %s AS MATERIALIZED (or drop NOT MATERIALIZED) if computing it once and reading the stored result is cheaper than repeating the query.", cte.name),
+					Severity:       types.SeverityLow,
+				})
+			}
+			continue
+		}
+
+		size := cteSizeDescription(root, cte.name)
+		if cte.references == 1 && !cte.recursive {
+			description := fmt.Sprintf("CTE %s is referenced once but materialized, so it is computed in full and the outer query cannot push its filters or join conditions into it.", cte.name)
+			recommendation := fmt.Sprintf("Declare it as %s AS NOT MATERIALIZED (PostgreSQL 12+) so the planner inlines it like a subquery.", cte.name)
+			if cte.materialized == "CTEMaterializeAlways" {
+				description = fmt.Sprintf("CTE %s is referenced once but declared MATERIALIZED, so it is computed in full and the outer query cannot push its filters or join conditions into it.", cte.name)
+				recommendation = fmt.Sprintf("Drop MATERIALIZED from %s unless it is used as an optimization fence on purpose; the planner then inlines it like a subquery.", cte.name)
+			}
+			if filter, ok := cteScanFilter(scans[0], cte.name); ok {
+				description += " " + filter
+			}
+			suggestions = append(suggestions, types.Suggestion{
+				Title:          "Single-use CTE is materialized",
+				Description:    description + size,
+				Recommendation: recommendation,
+				Severity:       types.SeverityMedium,
+			})
+			continue
+		}
+
+		for _, scan := range scans {
+			filter, ok := cteScanFilter(scan, cte.name)
+			if !ok {
+				continue
+			}
+			recommendation := fmt.Sprintf("Move the condition into the query of %s so it filters rows before they are stored.", cte.name)
+			if !cte.recursive && cte.materialized != "CTEMaterializeAlways" {
+				recommendation += " If the CTE is cheap to recompute, declaring it AS NOT MATERIALIZED lets the planner push the filter down for each reference."
+			}
+			suggestions = append(suggestions, types.Suggestion{
+				Title:          "Filter not pushed into CTE",
+				Description:    filter + size,
+				Recommendation: recommendation,
+				Severity:       types.SeverityMedium,
+			})
+			break
+		}
+	}
+
+	return suggestions, nil
+}
+
+func collectCTEs(ast map[string]any) []cteDefinition {
+	ctes := make([]cteDefinition, 0)
+	WalkAST(ast, func(node map[string]any) {
+		stmt, ok := node["SelectStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		withClause, ok := stmt["withClause"].(map[string]any)
+		if !ok {
+			return
+		}
+		recursive, _ := withClause["recursive"].(bool)
+		items, _ := withClause["ctes"].([]any)
+		for _, item := range items {
+			cte, ok := ChildNode(item, "CommonTableExpr")
+			if !ok {
+				continue
+			}
+			name, _ := cte["ctename"].(string)
+			materialized, _ := cte["ctematerialized"].(string)
+
+			references := 0
+			WalkAST(stmt, func(inner map[string]any) {
+				rangeVar, ok := inner["RangeVar"].(map[string]any)
+				if !ok {
+					return
+				}
+				if _, hasSchema := rangeVar["schemaname"]; !hasSchema && rangeVar["relname"] == name {
+					references++
+				}
+			})
+
+			ctes = append(ctes, cteDefinition{
+				name:         name,
+				materialized: materialized,
+				recursive:    recursive,
+				references:   references,
+			})
+		}
+	})
+	return ctes
+}
+
+func cteScanFilter(scan map[string]any, name string) (string, bool) {
+	filter := getString(scan, "Filter")
+	if filter == "" {
+		return "", false
+	}
+	description := fmt.Sprintf("The filter %s is applied on the CTE Scan of %s after the CTE has been computed", filter, name)
+	if removed, ok := getFloat(scan, "Rows Removed by Filter"); ok {
+		loops, ok := getFloat(scan, "Actual Loops")
+		if !ok || loops == 0 {
+			loops = 1
+		}
+		description += fmt.Sprintf(", discarding %.0f rows", removed*loops)
+	}
+	return description + ".", true
+}
+
+func cteSizeDescription(root map[string]any, name string) string {
+	var cteNode map[string]any
+	traversePlan(root, func(node map[string]any) {
+		if cteNode == nil && getString(node, "Subplan Name") == "CTE "+name {
+			cteNode = node
+		}
+	})
+	if cteNode == nil {
+		return ""
+	}
+
+	rows := planNodeRows(cteNode)
+	width, ok := getFloat(cteNode, "Plan Width")
+	if !ok {
+		return fmt.Sprintf(" It produces %.0f rows.", rows)
+	}
+	return fmt.Sprintf(" It produces %.0f rows of %.0f bytes, about %s held in memory or spilled to disk.", rows, width, formatBytes(rows*width))
+}
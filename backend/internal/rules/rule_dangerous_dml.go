@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const defaultInsertSelectMinRows = 100000
+
+type DangerousDMLRule struct {
+	MinInsertRows float64
+}
+
+func NewDangerousDMLRule() *DangerousDMLRule {
+	return &DangerousDMLRule{MinInsertRows: defaultInsertSelectMinRows}
+}
+
+func (r *DangerousDMLRule) Name() string {
+	return "DangerousDML"
+}
+
+func (r *DangerousDMLRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+
+	WalkAST(input.AST, func(node map[string]any) {
+		for _, kind := range []string{"UpdateStmt", "DeleteStmt"} {
+			stmt, ok := node[kind].(map[string]any)
+			if !ok {
+				continue
+			}
+			if suggestion, ok := unboundedWriteSuggestion(kind, stmt); ok {
+				suggestions = append(suggestions, suggestion)
+			}
+		}
+
+		stmt, ok := node["InsertStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		if suggestion, ok := r.insertSelectSuggestion(stmt, input.Plan); ok {
+			suggestions = append(suggestions, suggestion)
+		}
+	})
+
+	return suggestions, nil
+}
+
+func unboundedWriteSuggestion(kind string, stmt map[string]any) (types.Suggestion, bool) {
+	relation, _ := stmt["relation"].(map[string]any)
+	table, _ := relation["relname"].(string)
+	verb, extraKey, extraKeyword := "UPDATE", "fromClause", "FROM"
+	if kind == "DeleteStmt" {
+		verb, extraKey, extraKeyword = "DELETE", "usingClause", "USING"
+	}
+
+	if _, ok := stmt["whereClause"]; !ok {
+		recommendation := "Add a WHERE clause that selects the rows to change. To rewrite the whole table, update it in key-range batches so each transaction stays short."
+		description := fmt.Sprintf("UPDATE %s has no WHERE clause and rewrites every row of the table in one transaction.", table)
+		if kind == "DeleteStmt" {
+			description = fmt.Sprintf("DELETE FROM %s has no WHERE clause and removes every row of the table.", table)
+			recommendation = fmt.Sprintf("Add a WHERE clause that selects the rows to delete. If emptying %s is intended, TRUNCATE is faster and does not leave dead tuples behind.", table)
+		}
+		return types.Suggestion{
+			Title:          fmt.Sprintf("%s without WHERE", verb),
+			Description:    description,
+			Recommendation: recommendation,
+			Severity:       types.SeverityHigh,
+		}, true
+	}
+
+	extra, _ := stmt[extraKey].([]any)
+	if len(extra) == 0 {
+		return types.Suggestion{}, false
+	}
+	// Treat the target as one more FROM item so the usual join analysis
+	// tells whether every extra relation is tied to it.
+	groups, _, ok := unjoinedRelations(map[string]any{
+		"fromClause":  append([]any{map[string]any{"RangeVar": relation}}, extra...),
+		"whereClause": stmt["whereClause"],
+	})
+	if !ok {
+		return types.Suggestion{}, false
+	}
+
+	return types.Suggestion{
+		Title: fmt.Sprintf("%s ... %s without join condition", verb, extraKeyword),
+		Description: fmt.Sprintf("%s on %s lists %s in %s, but no predicate links it to the target, so every target row matching the rest of the WHERE clause is affected.",
+			verb, table, strings.Join(groups[1], ", "), extraKeyword),
+		Recommendation: fmt.Sprintf("Add the join condition between %s and %s to the WHERE clause, or use EXISTS if the relation is only a filter.", groups[0][0], groups[1][0]),
+		Severity:       types.SeverityHigh,
+	}, true
+}
+
+func (r *DangerousDMLRule) insertSelectSuggestion(stmt map[string]any, plan map[string]any) (types.Suggestion, bool) {
+	source, ok := ChildNode(stmt["selectStmt"], "SelectStmt")
+	if !ok {
+		return types.Suggestion{}, false
+	}
+	if _, limited := source["limitCount"]; limited {
+		return types.Suggestion{}, false
+	}
+	if _, ok := source["fromClause"]; !ok {
+		// INSERT ... VALUES has no source relation.
+		return types.Suggestion{}, false
+	}
+
+	relation, _ := stmt["relation"].(map[string]any)
+	table, _ := relation["relname"].(string)
+
+	description := fmt.Sprintf("INSERT INTO %s ... SELECT copies rows without a LIMIT", table)
+	rows, hasRows := insertSourceRows(plan)
+	switch {
+	case hasRows && rows >= r.MinInsertRows:
+		description += fmt.Sprintf("; the source produces %.0f rows, all written in one transaction.", rows)
+	case !hasRows:
+		if _, filtered := source["whereClause"]; filtered {
+			return types.Suggestion{}, false
+		}
+		description += fmt.Sprintf(" or WHERE clause, so it copies the whole of %s in one transaction.", strings.Join(fromRelationNames(source), ", "))
+	default:
+		return types.Suggestion{}, false
+	}
+
+	return types.Suggestion{
+		Title:          "Unbounded INSERT ... SELECT",
+		Description:    description,
+		Recommendation: "Copy the rows in batches by key range (WHERE id > $last ORDER BY id LIMIT n) in separate transactions to keep locks, WAL bursts and replication lag small.",
+		Severity:       types.SeverityHigh,
+	}, true
+}
+
+func insertSourceRows(plan map[string]any) (float64, bool) {
+	root := extractPlanRoot(plan)
+	if getString(root, "Node Type") != "ModifyTable" {
+		return 0, false
+	}
+	children, _ := root["Plans"].([]any)
+	if len(children) == 0 {
+		return 0, false
+	}
+	child, ok := children[0].(map[string]any)
+	if !ok {
+		return 0, false
+	}
+	return planNodeRows(child), true
+}
+
+func fromRelationNames(stmt map[string]any) []string {
+	names := make([]string, 0)
+	from, _ := stmt["fromClause"].([]any)
+	walkPredicate(from, func(node map[string]any) {
+		if rangeVar, ok := node["RangeVar"].(map[string]any); ok {
+			name, _ := rangeVar["relname"].(string)
+			names = append(names, name)
+		}
+	})
+	return names
+}
@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	minWorkMemKB = 4 * 1024
+	// A sort that fits in memory needs noticeably more space than its
+	// on-disk runs, so the spill size is padded before rounding.
+	workMemHeadroom = 1.5
+)
+
+type DiskSpillRule struct{}
+
+func NewDiskSpillRule() *DiskSpillRule {
+	return &DiskSpillRule{}
+}
+
+func (r *DiskSpillRule) Name() string {
+	return "DiskSpill"
+}
+
+func (r *DiskSpillRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	root := extractPlanRoot(input.Plan)
+	if root == nil {
+		return nil, nil
+	}
+
+	suggestions := make([]types.Suggestion, 0)
+	traversePlan(root, func(node map[string]any) {
+		reason, neededKB, ok := detectDiskSpill(node)
+		if !ok {
+			return
+		}
+
+		description := fmt.Sprintf("%s spilled to disk: %s.", describePlanNode(node), reason)
+		recommendation := "The node exceeded work_mem. Reduce the amount of data reaching it or raise work_mem for this query."
+		if neededKB > 0 {
+			description += fmt.Sprintf(" Roughly %s of memory is needed to stay in memory.", formatBytes(neededKB*1024))
+			recommendation = fmt.Sprintf("Raise work_mem for this query only, e.g. SET LOCAL work_mem = '%s' inside the transaction, instead of changing it globally; every such node in every session can allocate that much.",
+				suggestWorkMem(neededKB))
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Work memory exceeded, node spilled to disk",
+			Description:    description,
+			Recommendation: recommendation,
+			Severity:       types.SeverityMedium,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func detectDiskSpill(node map[string]any) (string, float64, bool) {
+	switch getString(node, "Node Type") {
+	case "Sort", "Incremental Sort":
+		method := getString(node, "Sort Method")
+		if getString(node, "Sort Space Type") != "Disk" && !strings.HasPrefix(method, "external") {
+			return "", 0, false
+		}
+		used, _ := getFloat(node, "Sort Space Used")
+		return fmt.Sprintf("sort method %q wrote %s to disk", method, formatBytes(used*1024)), used * workMemHeadroom, true
+	case "Hash":
+		batches, _ := getFloat(node, "Hash Batches")
+		original, _ := getFloat(node, "Original Hash Batches")
+		if batches <= 1 && batches <= original {
+			return "", 0, false
+		}
+		peak, _ := getFloat(node, "Peak Memory Usage")
+		reason := fmt.Sprintf("hash table split into %.0f batches", batches)
+		if original > 0 && batches > original {
+			reason += fmt.Sprintf(" (planned %.0f)", original)
+		}
+		return reason, peak * batches, true
+	case "Aggregate":
+		batches, _ := getFloat(node, "HashAgg Batches")
+		disk, _ := getFloat(node, "Disk Usage")
+		if batches <= 1 && disk <= 0 {
+			return "", 0, false
+		}
+		peak, _ := getFloat(node, "Peak Memory Usage")
+		return fmt.Sprintf("hash aggregate used %.0f batches and %s of disk", batches, formatBytes(disk*1024)), peak + disk, true
+	}
+	return "", 0, false
+}
+
+func suggestWorkMem(neededKB float64) string {
+	value := float64(minWorkMemKB)
+	for value < neededKB {
+		value *= 2
+	}
+	if value >= 1024*1024 {
+		return fmt.Sprintf("%.0fGB", math.Ceil(value/(1024*1024)))
+	}
+	return fmt.Sprintf("%.0fMB", value/1024)
+}
@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultFilterSelectivityFactor     = 2
+	defaultFilterSelectivityMinRemoved = 1000
+)
+
+type FilterSelectivityRule struct {
+	Factor     float64
+	MinRemoved float64
+}
+
+func NewFilterSelectivityRule() *FilterSelectivityRule {
+	return &FilterSelectivityRule{
+		Factor:     defaultFilterSelectivityFactor,
+		MinRemoved: defaultFilterSelectivityMinRemoved,
+	}
+}
+
+func (r *FilterSelectivityRule) Name() string {
+	return "FilterSelectivity"
+}
+
+func (r *FilterSelectivityRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	root := extractPlanRoot(input.Plan)
+	if root == nil {
+		return nil, nil
+	}
+
+	suggestions := make([]types.Suggestion, 0)
+	traversePlan(root, func(node map[string]any) {
+		nodeType := getString(node, "Node Type")
+		relation := getString(node, "Relation Name")
+		// Plain sequential scans are weighed by SeqScanRule.
+		if relation == "" || nodeType == "Seq Scan" || !strings.HasSuffix(nodeType, "Scan") {
+			return
+		}
+
+		actualRows, ok := getFloat(node, "Actual Rows")
+		if !ok {
+			return
+		}
+		loops, ok := getFloat(node, "Actual Loops")
+		if !ok || loops == 0 {
+			loops = 1
+		}
+		removedByFilter, _ := getFloat(node, "Rows Removed by Filter")
+		removedByRecheck, _ := getFloat(node, "Rows Removed by Index Recheck")
+
+		returned := actualRows * loops
+		removed := (removedByFilter + removedByRecheck) * loops
+		if removed < r.MinRemoved || removed < returned*r.Factor {
+			return
+		}
+
+		filter := getString(node, "Filter")
+		description := fmt.Sprintf("%s returned %.0f rows but discarded %.0f after reading them", describePlanNode(node), returned, removed)
+		if filter != "" && removedByFilter > 0 {
+			description += fmt.Sprintf("; filter %s removed %.0f", filter, removedByFilter*loops)
+		}
+		if removedByRecheck > 0 {
+			description += fmt.Sprintf("; index recheck removed %.0f", removedByRecheck*loops)
+		}
+		description += "."
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Filter discards most scanned rows",
+			Description:    description,
+			Recommendation: filterSelectivityRecommendation(node, relation, filter, removedByRecheck > 0),
+			Severity:       types.SeverityMedium,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func filterSelectivityRecommendation(node map[string]any, relation, filter string, lossy bool) string {
+	filterColumns := extractFilterColumns(filter)
+	if len(filterColumns) == 0 {
+		if lossy {
+			return "The bitmap became lossy and rows are rechecked against the heap. Raise work_mem so the bitmap stays exact."
+		}
+		return fmt.Sprintf("Move the discarded rows out of the scan on %q with a more selective index.", relation)
+	}
+
+	indexName, indexCond := scanIndex(node)
+	if indexName == "" {
+		return fmt.Sprintf("Create an index on %s (%s) so the filter %s becomes an index condition, e.g. CREATE INDEX CONCURRENTLY ON %s (%s).",
+			relation, strings.Join(filterColumns, ", "), filter, relation, strings.Join(filterColumns, ", "))
+	}
+
+	columns := extractFilterColumns(indexCond)
+	for _, column := range filterColumns {
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return fmt.Sprintf("Index %s is used but does not cover the filter %s. Extend it with %s, e.g. CREATE INDEX CONCURRENTLY ON %s (%s), then drop %s once the new index is in use.",
+		indexName, filter, strings.Join(filterColumns, ", "), relation, strings.Join(columns, ", "), indexName)
+}
+
+func scanIndex(node map[string]any) (string, string) {
+	if name := getString(node, "Index Name"); name != "" {
+		return name, getString(node, "Index Cond")
+	}
+
+	var name, cond string
+	traversePlan(node, func(child map[string]any) {
+		if name != "" || getString(child, "Node Type") != "Bitmap Index Scan" {
+			return
+		}
+		name = getString(child, "Index Name")
+		cond = getString(child, "Index Cond")
+	})
+	return name, cond
+}
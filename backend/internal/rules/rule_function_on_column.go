@@ -21,7 +21,7 @@ func (r *FunctionOnColumnRule) Apply(_ context.Context, input Input) ([]types.Su
 	suggestions := make([]types.Suggestion, 0)
 	seen := make(map[string]struct{})
 
-	walkAST(input.AST, func(node map[string]any) {
+	WalkAST(input.AST, func(node map[string]any) {
 		expr, ok := node["A_Expr"].(map[string]any)
 		if !ok {
 			return
@@ -0,0 +1,58 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type IndexConcurrentlyRule struct{}
+
+func NewIndexConcurrentlyRule() *IndexConcurrentlyRule {
+	return &IndexConcurrentlyRule{}
+}
+
+func (r *IndexConcurrentlyRule) Name() string {
+	return "IndexConcurrently"
+}
+
+func (r *IndexConcurrentlyRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	created := createdTables(input.AST)
+
+	WalkAST(input.AST, func(node map[string]any) {
+		stmt, ok := node["IndexStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		if concurrent, _ := stmt["concurrent"].(bool); concurrent {
+			return
+		}
+
+		relation, _ := stmt["relation"].(map[string]any)
+		table := QualifiedRelationName(relation)
+		// A table created by the same script is empty and not yet in use.
+		if _, ok := created[table]; ok {
+			return
+		}
+		index, _ := stmt["idxname"].(string)
+		if index == "" {
+			index = "the index"
+		}
+		kind := "CREATE INDEX"
+		if unique, _ := stmt["unique"].(bool); unique {
+			kind = "CREATE UNIQUE INDEX"
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:       "Index created without CONCURRENTLY",
+			Description: fmt.Sprintf("%s on %s takes a ShareLock on the table, blocking INSERT, UPDATE and DELETE until %s is fully built.", kind, table, index),
+			Recommendation: fmt.Sprintf("Use %s CONCURRENTLY outside a transaction block; it only takes a ShareUpdateExclusiveLock. If the build fails it leaves an INVALID index behind, so drop it with DROP INDEX CONCURRENTLY and retry.",
+				kind),
+			Severity: types.SeverityHigh,
+		})
+	})
+
+	return suggestions, nil
+}
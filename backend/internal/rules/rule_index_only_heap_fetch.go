@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultHeapFetchMinFetches = 1000
+	defaultHeapFetchMinRatio   = 0.1
+	heapFetchHighRatio         = 0.5
+)
+
+const tableVacuumStatsQuery = `SELECT n_live_tup, n_dead_tup, last_vacuum, last_autovacuum
+FROM pg_stat_user_tables
+WHERE relname = $1
+ORDER BY schemaname = current_schema() DESC
+LIMIT 1`
+
+type IndexOnlyHeapFetchRule struct {
+	MinFetches float64
+	MinRatio   float64
+}
+
+func NewIndexOnlyHeapFetchRule() *IndexOnlyHeapFetchRule {
+	return &IndexOnlyHeapFetchRule{
+		MinFetches: defaultHeapFetchMinFetches,
+		MinRatio:   defaultHeapFetchMinRatio,
+	}
+}
+
+func (r *IndexOnlyHeapFetchRule) Name() string {
+	return "IndexOnlyHeapFetch"
+}
+
+type tableVacuumStats struct {
+	liveTuples     int64
+	deadTuples     int64
+	lastVacuum     *time.Time
+	lastAutovacuum *time.Time
+}
+
+func (r *IndexOnlyHeapFetchRule) Apply(ctx context.Context, input Input) ([]types.Suggestion, error) {
+	root := extractPlanRoot(input.Plan)
+	if root == nil {
+		return nil, nil
+	}
+
+	type finding struct {
+		node     map[string]any
+		relation string
+		fetches  float64
+		rows     float64
+	}
+	findings := make([]finding, 0)
+	traversePlan(root, func(node map[string]any) {
+		if getString(node, "Node Type") != "Index Only Scan" {
+			return
+		}
+		fetches, ok := getFloat(node, "Heap Fetches")
+		if !ok || fetches < r.MinFetches {
+			return
+		}
+		rows := planNodeRows(node)
+		if rows > 0 && fetches/rows < r.MinRatio {
+			return
+		}
+		findings = append(findings, finding{
+			node:     node,
+			relation: getString(node, "Relation Name"),
+			fetches:  fetches,
+			rows:     rows,
+		})
+	})
+
+	suggestions := make([]types.Suggestion, 0, len(findings))
+	for _, f := range findings {
+		ratio := 1.0
+		if f.rows > 0 {
+			ratio = f.fetches / f.rows
+		}
+
+		description := fmt.Sprintf("%s had to visit the heap %.0f times for %.0f returned rows (%.0f%%): the visibility map is stale, so the scan is not really index-only.",
+			describePlanNode(f.node), f.fetches, f.rows, ratio*100)
+		if stats, ok := loadTableVacuumStats(ctx, input.DB, f.relation); ok {
+			description += " " + describeVacuumStats(f.relation, stats)
+		}
+
+		severity := types.SeverityMedium
+		if ratio >= heapFetchHighRatio {
+			severity = types.SeverityHigh
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:       "Index-only scan fetching heap pages",
+			Description: description,
+			Recommendation: fmt.Sprintf("Run VACUUM (ANALYZE) %s to refresh its visibility map, and make autovacuum visit it more often, e.g. ALTER TABLE %s SET (autovacuum_vacuum_scale_factor = 0.02, autovacuum_vacuum_insert_scale_factor = 0.02).",
+				f.relation, f.relation),
+			Severity: severity,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// loadTableVacuumStats is best effort: missing statistics only make the
+// advice less specific, so query errors are not reported.
+func loadTableVacuumStats(ctx context.Context, db Querier, relation string) (tableVacuumStats, bool) {
+	var stats tableVacuumStats
+	if db == nil || relation == "" {
+		return stats, false
+	}
+
+	err := db.QueryRow(ctx, tableVacuumStatsQuery, relation).Scan(
+		&stats.liveTuples,
+		&stats.deadTuples,
+		&stats.lastVacuum,
+		&stats.lastAutovacuum,
+	)
+	if err != nil {
+		return stats, false
+	}
+	return stats, true
+}
+
+func describeVacuumStats(relation string, stats tableVacuumStats) string {
+	lastVacuum := stats.lastVacuum
+	if stats.lastAutovacuum != nil && (lastVacuum == nil || stats.lastAutovacuum.After(*lastVacuum)) {
+		lastVacuum = stats.lastAutovacuum
+	}
+
+	vacuumed := "has never been vacuumed"
+	if lastVacuum != nil {
+		vacuumed = "was last vacuumed " + lastVacuum.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf("pg_stat_user_tables reports %d dead and %d live tuples in %s, which %s.",
+		stats.deadTuples, stats.liveTuples, relation, vacuumed)
+}
@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultInListMinItems  = 100
+	defaultInListHighItems = 1000
+)
+
+type LargeInListRule struct {
+	MinItems  int
+	HighItems int
+}
+
+func NewLargeInListRule() *LargeInListRule {
+	return &LargeInListRule{
+		MinItems:  defaultInListMinItems,
+		HighItems: defaultInListHighItems,
+	}
+}
+
+func (r *LargeInListRule) Name() string {
+	return "LargeInList"
+}
+
+func (r *LargeInListRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	planning, hasPlanning := getFloat(input.Plan, "Planning Time")
+	execution, _ := getFloat(input.Plan, "Execution Time")
+
+	param := maxParamNumber(input.AST) + 1
+	suggestions := make([]types.Suggestion, 0)
+	WalkAST(input.AST, func(node map[string]any) {
+		expr, ok := node["A_Expr"].(map[string]any)
+		if !ok {
+			return
+		}
+		if kind, _ := expr["kind"].(string); kind != "AEXPR_IN" {
+			return
+		}
+		list, _ := ChildNode(expr["rexpr"], "List")
+		items, _ := list["items"].([]any)
+		if len(items) < r.MinItems {
+			return
+		}
+
+		column := extractColumnName(expr["lexpr"])
+		if column == "" {
+			column = "expression"
+		}
+		negated := exprOperator(expr) == "<>"
+		keyword, arrayOp := "IN", "= ANY"
+		if negated {
+			keyword, arrayOp = "NOT IN", "<> ALL"
+		}
+
+		description := fmt.Sprintf("%s %s lists %d values; every value is parsed, planned and compared separately.", column, keyword, len(items))
+		severity := types.SeverityMedium
+		if len(items) >= r.HighItems {
+			severity = types.SeverityHigh
+		}
+		if hasPlanning {
+			description += fmt.Sprintf(" Planning took %.2f ms", planning)
+			if execution > 0 {
+				description += fmt.Sprintf(" against %.2f ms of execution", execution)
+				if planning >= execution {
+					severity = types.SeverityHigh
+				}
+			}
+			description += "."
+		}
+
+		array := fmt.Sprintf("$%d", param)
+		if elementType, ok := inListElementType(items); ok {
+			array += "::" + elementType + "[]"
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Large IN list",
+			Description:    description,
+			Recommendation: fmt.Sprintf("Pass the values as one array parameter (%s %s(%s)), join against a VALUES list, or load them into a temporary table and join it. The query text then stays the same regardless of the number of values.", column, arrayOp, array),
+			Severity:       severity,
+		})
+	})
+
+	return suggestions, nil
+}
+
+// inListElementType infers the array element type from the list's
+// literals. Lists with other expressions or mixed literal types report
+// false so the cast is left to the caller.
+func inListElementType(items []any) (string, bool) {
+	elementType := ""
+	for _, item := range items {
+		aConst, ok := ChildNode(item, "A_Const")
+		if !ok {
+			return "", false
+		}
+		var itemType string
+		switch {
+		case aConst["ival"] != nil:
+			itemType = "bigint"
+		case aConst["fval"] != nil:
+			itemType = "numeric"
+		case aConst["sval"] != nil:
+			itemType = "text"
+		case aConst["boolval"] != nil:
+			itemType = "boolean"
+		default:
+			return "", false
+		}
+		// Integers and decimals together still fit a numeric array.
+		if (elementType == "bigint" && itemType == "numeric") || (elementType == "numeric" && itemType == "bigint") {
+			itemType = "numeric"
+		} else if elementType != "" && elementType != itemType {
+			return "", false
+		}
+		elementType = itemType
+	}
+	return elementType, elementType != ""
+}
@@ -22,7 +22,7 @@ func (r *LeadingWildcardRule) Apply(_ context.Context, input Input) ([]types.Sug
 	suggestions := make([]types.Suggestion, 0)
 	seen := make(map[string]struct{})
 
-	walkAST(input.AST, func(node map[string]any) {
+	WalkAST(input.AST, func(node map[string]any) {
 		expr, ok := node["A_Expr"].(map[string]any)
 		if !ok {
 			return
@@ -0,0 +1,165 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultNestedLoopMinLoops     = 1000
+	defaultNestedLoopMinLoopCost  = 10
+	defaultNestedLoopMinTotalTime = 100
+	nestedLoopHighTotalTime       = 1000
+	nestedLoopMisestimateFactor   = 10
+)
+
+type NestedLoopRule struct {
+	MinLoops     float64
+	MinLoopCost  float64
+	MinTotalTime float64
+}
+
+func NewNestedLoopRule() *NestedLoopRule {
+	return &NestedLoopRule{
+		MinLoops:     defaultNestedLoopMinLoops,
+		MinLoopCost:  defaultNestedLoopMinLoopCost,
+		MinTotalTime: defaultNestedLoopMinTotalTime,
+	}
+}
+
+func (r *NestedLoopRule) Name() string {
+	return "NestedLoop"
+}
+
+func (r *NestedLoopRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	root := extractPlanRoot(input.Plan)
+	if root == nil {
+		return nil, nil
+	}
+
+	executionTime, _ := getFloat(input.Plan, "Execution Time")
+
+	suggestions := make([]types.Suggestion, 0)
+	traversePlan(root, func(node map[string]any) {
+		if getString(node, "Node Type") != "Nested Loop" {
+			return
+		}
+
+		outer, inner := joinChildren(node)
+		if inner == nil {
+			return
+		}
+
+		loops, _ := getFloat(inner, "Actual Loops")
+		if loops < r.MinLoops {
+			return
+		}
+		loopCost, _ := getFloat(inner, "Total Cost")
+		loopTime, _ := getFloat(inner, "Actual Total Time")
+		loopRows, _ := getFloat(inner, "Actual Rows")
+		totalTime := loopTime * loops
+		if loopCost < r.MinLoopCost && totalTime < r.MinTotalTime {
+			return
+		}
+
+		severity := types.SeverityMedium
+		if totalTime >= nestedLoopHighTotalTime || (executionTime > 0 && totalTime >= executionTime/2) {
+			severity = types.SeverityHigh
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title: "Nested loop repeats its inner side many times",
+			Description: fmt.Sprintf("The inner side (%s) of a nested loop ran %.0f times at %.3f ms and cost %.1f per loop: %.1f ms, %.0f rows and %.0f cost units in total.",
+				describePlanNode(inner), loops, loopTime, loopCost, totalTime, loopRows*loops, loopCost*loops),
+			Recommendation: nestedLoopRecommendation(node, outer, inner),
+			Severity:       severity,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func joinChildren(node map[string]any) (outer, inner map[string]any) {
+	children, _ := node["Plans"].([]any)
+	for i, child := range children {
+		childNode, ok := child.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch getString(childNode, "Parent Relationship") {
+		case "Outer":
+			outer = childNode
+		case "Inner":
+			inner = childNode
+		default:
+			if i == 0 && outer == nil {
+				outer = childNode
+			} else if i == 1 && inner == nil {
+				inner = childNode
+			}
+		}
+	}
+	return outer, inner
+}
+
+func nestedLoopRecommendation(node, outer, inner map[string]any) string {
+	if outer != nil {
+		planRows, _ := getFloat(outer, "Plan Rows")
+		actualRows, hasActual := getFloat(outer, "Actual Rows")
+		outerLoops, _ := getFloat(outer, "Actual Loops")
+		if outerLoops == 0 {
+			outerLoops = 1
+		}
+		if hasActual && actualRows*outerLoops >= nestedLoopMisestimateFactor*max(planRows*outerLoops, 1) {
+			return fmt.Sprintf("The planner expected %.0f outer rows from %s but got %.0f, which made a nested loop look cheap. Fix the outer estimate (ANALYZE, a higher statistics target or CREATE STATISTICS) so it can choose a hash or merge join.",
+				planRows*outerLoops, describePlanNode(outer), actualRows*outerLoops)
+		}
+	}
+
+	relation, alias, usesIndex := "", "", false
+	traversePlan(inner, func(child map[string]any) {
+		if relation == "" {
+			relation = getString(child, "Relation Name")
+			alias = getString(child, "Alias")
+		}
+		if getString(child, "Index Cond") != "" {
+			usesIndex = true
+		}
+	})
+
+	if !usesIndex && relation != "" {
+		cond := getString(node, "Join Filter")
+		if cond == "" {
+			cond = getString(inner, "Filter")
+		}
+		if columns := joinKeyColumns(cond, alias); len(columns) > 0 {
+			return fmt.Sprintf("Each loop scans %q without an index. Index the inner join key, e.g. CREATE INDEX CONCURRENTLY ON %s (%s), or let the planner use a hash join.",
+				relation, relation, strings.Join(columns, ", "))
+		}
+		return fmt.Sprintf("Each loop scans %q without an index. Index its join key or let the planner use a hash join.", relation)
+	}
+
+	return "The loop count makes the per-loop work add up. Compare against a hash or merge join (SET enable_nestloop = off in a test session) and, if it wins, check why the planner underestimated the outer side."
+}
+
+var qualifiedColumnPattern = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z_][A-Za-z0-9_]*)\b`)
+
+func joinKeyColumns(cond, alias string) []string {
+	columns := make([]string, 0)
+	if alias != "" {
+		for _, match := range qualifiedColumnPattern.FindAllStringSubmatch(cond, -1) {
+			if match[1] == alias && !slices.Contains(columns, match[2]) {
+				columns = append(columns, match[2])
+			}
+		}
+	}
+	if len(columns) > 0 {
+		return columns
+	}
+	return extractFilterColumns(cond)
+}
@@ -0,0 +1,278 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type NotInSubqueryRule struct{}
+
+func NewNotInSubqueryRule() *NotInSubqueryRule {
+	return &NotInSubqueryRule{}
+}
+
+func (r *NotInSubqueryRule) Name() string {
+	return "NotInSubquery"
+}
+
+func (r *NotInSubqueryRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	findings := 0
+	columns := make([]string, 0)
+	forEachNotIn(input.AST, func(stmt map[string]any, holder map[string]any, subLink map[string]any) {
+		findings++
+		if column := extractColumnName(subLink["testexpr"]); column != "" {
+			columns = append(columns, column)
+		}
+	})
+	if findings == 0 {
+		return nil, nil
+	}
+
+	subject := "a subquery"
+	if len(columns) > 0 {
+		subject = "a subquery on " + strings.Join(columns, ", ")
+	}
+	description := fmt.Sprintf("NOT IN over %s returns no rows at all as soon as the subquery yields a NULL, and PostgreSQL cannot plan it as an anti-join.", subject)
+	if planHasSubPlanFilter(input.Plan) {
+		description += " The plan evaluates it as a SubPlan filter for every outer row."
+	}
+
+	// Without a rewrite the suggestion still explains the NULL trap.
+	rewrite, err := rewriteNotInAsNotExists(input.AST)
+	if err != nil {
+		rewrite = ""
+	}
+
+	recommendation := "Rewrite the predicate as NOT EXISTS with a correlated condition so it is planned as a hash or merge anti-join. The meaning changes for NULL keys: NOT EXISTS keeps outer rows whose key is NULL and ignores NULLs returned by the subquery, so confirm that is the intended result."
+	if rewrite != "" {
+		recommendation += " Check the correlation columns in the rewritten query before using it."
+	}
+
+	return []types.Suggestion{{
+		Title:          "NOT IN with subquery",
+		Description:    description,
+		Recommendation: recommendation,
+		Severity:       types.SeverityMedium,
+		Rewrite:        rewrite,
+	}}, nil
+}
+
+// forEachNotIn calls visit for every `expr NOT IN (subquery)` and
+// `expr <> ALL (subquery)` predicate. holder is the map that owns the
+// predicate node so the caller can replace it in place.
+func forEachNotIn(ast any, visit func(stmt, holder, subLink map[string]any)) {
+	WalkAST(ast, func(node map[string]any) {
+		stmt, ok := node["SelectStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		walkPredicate(stmt["whereClause"], func(holder map[string]any) {
+			if boolExpr, ok := holder["BoolExpr"].(map[string]any); ok {
+				if boolop, _ := boolExpr["boolop"].(string); boolop != "NOT_EXPR" {
+					return
+				}
+				args, _ := boolExpr["args"].([]any)
+				if len(args) != 1 {
+					return
+				}
+				arg, _ := args[0].(map[string]any)
+				if subLink, ok := arg["SubLink"].(map[string]any); ok && subLinkOperator(subLink, "ANY_SUBLINK", "=") {
+					visit(stmt, holder, subLink)
+				}
+				return
+			}
+			if subLink, ok := holder["SubLink"].(map[string]any); ok && subLinkOperator(subLink, "ALL_SUBLINK", "<>") {
+				visit(stmt, holder, subLink)
+			}
+		})
+	})
+}
+
+func subLinkOperator(subLink map[string]any, linkType, op string) bool {
+	if kind, _ := subLink["subLinkType"].(string); kind != linkType {
+		return false
+	}
+	names, _ := subLink["operName"].([]any)
+	if len(names) == 0 {
+		// IN (subquery) is parsed without an explicit operator.
+		return op == "="
+	}
+	last, _ := names[len(names)-1].(map[string]any)
+	str, _ := last["String"].(map[string]any)
+	name, _ := str["sval"].(string)
+	return name == op
+}
+
+func rewriteNotInAsNotExists(ast map[string]any) (string, error) {
+	clone, err := cloneAST(ast)
+	if err != nil {
+		return "", err
+	}
+
+	taken := relationNames(clone)
+	rewritten := 0
+	forEachNotIn(clone, func(stmt, holder, subLink map[string]any) {
+		exists, ok := buildNotExists(stmt, subLink, taken)
+		if !ok {
+			return
+		}
+		delete(holder, "SubLink")
+		holder["BoolExpr"] = map[string]any{
+			"boolop": "NOT_EXPR",
+			"args":   []any{map[string]any{"SubLink": exists}},
+		}
+		rewritten++
+	})
+	if rewritten == 0 {
+		return "", nil
+	}
+
+	return deparseAST(clone)
+}
+
+// buildNotExists turns the NOT IN subquery into a correlated EXISTS. The
+// inner relation gets a fresh alias so a self-referencing subquery does
+// not compare a column with itself, and the outer columns must be
+// qualified so they cannot bind to the inner relation.
+func buildNotExists(outer, subLink map[string]any, taken map[string]bool) (map[string]any, bool) {
+	subselect, ok := ChildNode(subLink["subselect"], "SelectStmt")
+	if !ok {
+		return nil, false
+	}
+	if op, _ := subselect["op"].(string); op != "" && op != "SETOP_NONE" {
+		return nil, false
+	}
+	// A correlated EXISTS applies ORDER BY/LIMIT per outer row, not to the
+	// subquery's result as a whole.
+	for _, key := range []string{"limitCount", "limitOffset", "sortClause"} {
+		if _, ok := subselect[key]; ok {
+			return nil, false
+		}
+	}
+	targets, _ := subselect["targetList"].([]any)
+	if len(targets) != 1 {
+		return nil, false
+	}
+	target, ok := ChildNode(targets[0], "ResTarget")
+	if !ok {
+		return nil, false
+	}
+	from, _ := subselect["fromClause"].([]any)
+	if len(from) != 1 {
+		return nil, false
+	}
+	rangeVar, ok := ChildNode(from[0], "RangeVar")
+	if !ok {
+		return nil, false
+	}
+
+	if _, isRow := ChildNode(subLink["testexpr"], "RowExpr"); isRow {
+		return nil, false
+	}
+	if fields, ok := extractColumnRefFields(target["val"]); !ok || fields[len(fields)-1] == "*" {
+		return nil, false
+	}
+	outerExpr, ok := qualifyColumns(subLink["testexpr"], singleRelationName(outer))
+	if !ok {
+		return nil, false
+	}
+
+	alias := freshAlias(rangeVar, taken)
+	renameQualifier(subselect, singleRelationName(subselect), alias)
+	rangeVar["alias"] = map[string]any{"aliasname": alias}
+	innerExpr, _ := qualifyColumns(target["val"], alias)
+
+	subselect["targetList"] = []any{
+		map[string]any{"ResTarget": map[string]any{
+			"val": map[string]any{"A_Const": map[string]any{"ival": map[string]any{"ival": 1}}},
+		}},
+	}
+	appendWhereCondition(subselect, makeOpExpr("=", innerExpr, outerExpr))
+
+	return map[string]any{
+		"subLinkType": "EXISTS_SUBLINK",
+		"subselect":   map[string]any{"SelectStmt": subselect},
+	}, true
+}
+
+// qualifyColumns prefixes every unqualified column in expr with relation.
+// It reports false when expr has such a column but relation is unknown.
+func qualifyColumns(expr any, relation string) (any, bool) {
+	if fields, ok := extractColumnRefFields(expr); ok && len(fields) == 1 {
+		if relation == "" {
+			return nil, false
+		}
+		return makeColumnRef(relation, fields[0]), true
+	}
+
+	qualified := true
+	WalkAST(expr, func(node map[string]any) {
+		fields, ok := extractColumnRefFields(node)
+		if !ok || len(fields) != 1 {
+			return
+		}
+		if relation == "" {
+			qualified = false
+			return
+		}
+		node["ColumnRef"] = makeColumnRef(relation, fields[0])["ColumnRef"]
+	})
+	return expr, qualified
+}
+
+// renameQualifier points column references qualified with from at to.
+func renameQualifier(stmt map[string]any, from, to string) {
+	WalkAST(stmt, func(node map[string]any) {
+		fields, ok := extractColumnRefFields(node)
+		if !ok || len(fields) < 2 || fields[len(fields)-2] != from {
+			return
+		}
+		node["ColumnRef"] = makeColumnRef(to, fields[len(fields)-1])["ColumnRef"]
+	})
+}
+
+func relationNames(ast any) map[string]bool {
+	names := make(map[string]bool)
+	WalkAST(ast, func(node map[string]any) {
+		if rangeVar, ok := node["RangeVar"].(map[string]any); ok {
+			if name, _ := rangeVar["relname"].(string); name != "" {
+				names[name] = true
+			}
+		}
+		if alias, ok := node["alias"].(map[string]any); ok {
+			if name, _ := alias["aliasname"].(string); name != "" {
+				names[name] = true
+			}
+		}
+		if cte, ok := node["CommonTableExpr"].(map[string]any); ok {
+			if name, _ := cte["ctename"].(string); name != "" {
+				names[name] = true
+			}
+		}
+	})
+	return names
+}
+
+func freshAlias(rangeVar map[string]any, taken map[string]bool) string {
+	relname, _ := rangeVar["relname"].(string)
+	base := relname + "_sub"
+	alias := base
+	for i := 2; taken[alias]; i++ {
+		alias = fmt.Sprintf("%s%d", base, i)
+	}
+	taken[alias] = true
+	return alias
+}
+
+func planHasSubPlanFilter(plan map[string]any) bool {
+	found := false
+	traversePlan(extractPlanRoot(plan), func(node map[string]any) {
+		if strings.Contains(getString(node, "Filter"), "SubPlan") {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+// volatileDefaultFunctions are common defaults that are evaluated per row, so
+// adding a column with them rewrites the whole table.
+var volatileDefaultFunctions = map[string]struct{}{
+	"random":             {},
+	"gen_random_uuid":    {},
+	"uuid_generate_v1":   {},
+	"uuid_generate_v1mc": {},
+	"uuid_generate_v4":   {},
+	"clock_timestamp":    {},
+	"timeofday":          {},
+	"nextval":            {},
+	"txid_current":       {},
+}
+
+var serialTypes = map[string]struct{}{
+	"smallserial": {},
+	"serial":      {},
+	"bigserial":   {},
+	"serial2":     {},
+	"serial4":     {},
+	"serial8":     {},
+}
+
+type NotNullDefaultRule struct{}
+
+func NewNotNullDefaultRule() *NotNullDefaultRule {
+	return &NotNullDefaultRule{}
+}
+
+func (r *NotNullDefaultRule) Name() string {
+	return "NotNullDefault"
+}
+
+func (r *NotNullDefaultRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+
+	forEachAlterTableCmd(input.AST, func(table string, cmd map[string]any) {
+		switch subtype, _ := cmd["subtype"].(string); subtype {
+		case "AT_SetNotNull":
+			column, _ := cmd["name"].(string)
+			suggestions = append(suggestions, types.Suggestion{
+				Title:       "SET NOT NULL scans the table under an exclusive lock",
+				Description: fmt.Sprintf("ALTER COLUMN %s SET NOT NULL on %s holds an AccessExclusiveLock, blocking reads and writes, while it scans every row.", column, table),
+				Recommendation: fmt.Sprintf("On PostgreSQL 12+ first ADD CONSTRAINT %s_not_null CHECK (%s IS NOT NULL) NOT VALID, then VALIDATE CONSTRAINT (ShareUpdateExclusiveLock, no blocking), then SET NOT NULL, which reuses the validated check and skips the scan, and finally drop the check. %s",
+					column, column, lockTimeoutAdvice),
+				Severity: types.SeverityHigh,
+			})
+		case "AT_AddColumn":
+			columnDef, ok := ChildNode(cmd["def"], "ColumnDef")
+			if !ok {
+				return
+			}
+			column, _ := columnDef["colname"].(string)
+			notNull, hasDefault, volatile := columnConstraints(columnDef)
+
+			if volatile != "" {
+				suggestions = append(suggestions, types.Suggestion{
+					Title:       "Column added with a volatile default",
+					Description: fmt.Sprintf("ADD COLUMN %s on %s uses the volatile default %s, so PostgreSQL rewrites every row of the table while holding an AccessExclusiveLock.", column, table, volatile),
+					Recommendation: fmt.Sprintf("Add %s without a default, then ALTER COLUMN %s SET DEFAULT for new rows, backfill existing rows in batches, and add NOT NULL afterwards with a NOT VALID check constraint. %s",
+						column, column, lockTimeoutAdvice),
+					Severity: types.SeverityHigh,
+				})
+				return
+			}
+			if notNull && !hasDefault {
+				suggestions = append(suggestions, types.Suggestion{
+					Title:          "NOT NULL column added without a default",
+					Description:    fmt.Sprintf("ADD COLUMN %s NOT NULL on %s has no default, so it fails as soon as the table has rows.", column, table),
+					Recommendation: fmt.Sprintf("Add %s with a constant default (no rewrite on PostgreSQL 11+), or add it nullable, backfill it in batches and enforce NOT NULL with a NOT VALID check constraint that you validate afterwards.", column),
+					Severity:       types.SeverityHigh,
+				})
+			}
+		}
+	})
+
+	return suggestions, nil
+}
+
+// columnConstraints reports whether a column definition is NOT NULL, has a
+// default, and names the volatile default expression if it has one.
+func columnConstraints(columnDef map[string]any) (bool, bool, string) {
+	notNull, hasDefault, volatile := false, false, ""
+	if _, serial := serialTypes[typeNameString(columnDef["typeName"])]; serial {
+		hasDefault, volatile = true, "nextval() of the new serial sequence"
+	}
+
+	constraints, _ := columnDef["constraints"].([]any)
+	for _, raw := range constraints {
+		constraint, ok := ChildNode(raw, "Constraint")
+		if !ok {
+			continue
+		}
+		switch contype, _ := constraint["contype"].(string); contype {
+		case "CONSTR_NOTNULL", "CONSTR_PRIMARY":
+			notNull = true
+		case "CONSTR_IDENTITY":
+			hasDefault, volatile = true, "nextval() of the identity sequence"
+		case "CONSTR_DEFAULT":
+			hasDefault = true
+			WalkAST(constraint["raw_expr"], func(node map[string]any) {
+				if name, _, ok := extractFunctionCall(node); ok {
+					if _, isVolatile := volatileDefaultFunctions[name]; isVolatile {
+						volatile = name + "()"
+					}
+				}
+			})
+		}
+	}
+	return notNull, hasDefault, volatile
+}
@@ -0,0 +1,201 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultOffsetPaginationMinOffset = 1000
+	offsetPaginationHighOffset       = 10000
+)
+
+type OffsetPaginationRule struct {
+	MinOffset int64
+}
+
+func NewOffsetPaginationRule() *OffsetPaginationRule {
+	return &OffsetPaginationRule{MinOffset: defaultOffsetPaginationMinOffset}
+}
+
+func (r *OffsetPaginationRule) Name() string {
+	return "OffsetPagination"
+}
+
+func (r *OffsetPaginationRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	discarded, hasDiscarded := limitDiscardedRows(input.Plan)
+
+	WalkAST(input.AST, func(node map[string]any) {
+		stmt, ok := node["SelectStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		offset, ok := extractConstInt(stmt["limitOffset"])
+		if !ok || offset < r.MinOffset {
+			return
+		}
+
+		severity := types.SeverityMedium
+		if offset >= offsetPaginationHighOffset {
+			severity = types.SeverityHigh
+		}
+
+		description := fmt.Sprintf("OFFSET %d makes PostgreSQL produce and throw away %d rows before returning a page, so every later page gets slower.", offset, offset)
+		if hasDiscarded {
+			description += fmt.Sprintf(" The Limit node discarded %.0f rows in this run.", discarded)
+		}
+
+		suggestion := types.Suggestion{
+			Title:          "Deep OFFSET pagination",
+			Description:    description,
+			Severity:       severity,
+			Recommendation: "Switch to keyset pagination: ORDER BY a unique key (e.g. id) and filter on the last key of the previous page instead of using OFFSET.",
+		}
+
+		predicate, ok := keysetPredicate(stmt, maxParamNumber(input.AST)+1)
+		if !ok {
+			suggestions = append(suggestions, suggestion)
+			return
+		}
+		predicateSQL, err := deparseExpr(predicate)
+		if err != nil {
+			suggestions = append(suggestions, suggestion)
+			return
+		}
+
+		suggestion.Recommendation = fmt.Sprintf("Switch to keyset pagination: drop OFFSET and filter with %s, binding the ORDER BY values of the last row of the previous page. Make sure the ORDER BY ends with a unique column and is backed by an index.", predicateSQL)
+		// A failed rewrite still leaves the recommendation useful on its own.
+		if rewrite, err := rewriteKeysetPagination(input.AST, stmt, predicate); err == nil {
+			suggestion.Rewrite = rewrite
+		}
+		suggestions = append(suggestions, suggestion)
+	})
+
+	return suggestions, nil
+}
+
+func keysetPredicate(stmt map[string]any, firstParam int) (map[string]any, bool) {
+	sortClause, _ := stmt["sortClause"].([]any)
+	if len(sortClause) == 0 {
+		return nil, false
+	}
+
+	columns := make([][]string, 0, len(sortClause))
+	directions := make([]string, 0, len(sortClause))
+	for _, item := range sortClause {
+		sortBy, ok := ChildNode(item, "SortBy")
+		if !ok {
+			return nil, false
+		}
+		fields, ok := extractColumnRefFields(sortBy["node"])
+		if !ok || fields[len(fields)-1] == "*" {
+			return nil, false
+		}
+		columns = append(columns, fields)
+
+		op := ">"
+		if dir, _ := sortBy["sortby_dir"].(string); dir == "SORTBY_DESC" {
+			op = "<"
+		}
+		directions = append(directions, op)
+	}
+
+	// Fresh nodes per use keep the predicate a tree once it is spliced
+	// into the clone.
+	column := func(i int) map[string]any { return makeColumnRef(columns[i]...) }
+	param := func(i int) map[string]any { return makeParamRef(firstParam + i) }
+
+	if len(columns) == 1 {
+		return makeOpExpr(directions[0], column(0), param(0)), true
+	}
+
+	uniform := true
+	for _, op := range directions[1:] {
+		if op != directions[0] {
+			uniform = false
+		}
+	}
+	if uniform {
+		lrow := make([]any, len(columns))
+		rrow := make([]any, len(columns))
+		for i := range columns {
+			lrow[i] = column(i)
+			rrow[i] = param(i)
+		}
+		return makeOpExpr(directions[0], makeRowExpr(lrow...), makeRowExpr(rrow...)), true
+	}
+
+	// Mixed directions cannot use a row comparison, so expand it.
+	branches := make([]any, 0, len(columns))
+	for i := range columns {
+		parts := make([]any, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, makeOpExpr("=", column(j), param(j)))
+		}
+		parts = append(parts, makeOpExpr(directions[i], column(i), param(i)))
+		if len(parts) == 1 {
+			branches = append(branches, parts[0])
+			continue
+		}
+		branches = append(branches, makeBoolExpr("AND_EXPR", parts...))
+	}
+	return makeBoolExpr("OR_EXPR", branches...), true
+}
+
+func rewriteKeysetPagination(ast, stmt, predicate map[string]any) (string, error) {
+	offsetNode, _ := ChildNode(stmt["limitOffset"], "A_Const")
+	location := offsetNode["location"]
+
+	clone, err := cloneAST(ast)
+	if err != nil {
+		return "", err
+	}
+
+	rewritten := false
+	WalkAST(clone, func(node map[string]any) {
+		target, ok := node["SelectStmt"].(map[string]any)
+		if !ok || rewritten {
+			return
+		}
+		if offset, ok := ChildNode(target["limitOffset"], "A_Const"); !ok || offset["location"] != location {
+			return
+		}
+		delete(target, "limitOffset")
+		appendWhereCondition(target, predicate)
+		rewritten = true
+	})
+	if !rewritten {
+		return "", nil
+	}
+
+	return deparseAST(clone)
+}
+
+func limitDiscardedRows(plan map[string]any) (float64, bool) {
+	var discarded float64
+	found := false
+	traversePlan(extractPlanRoot(plan), func(node map[string]any) {
+		if found || getString(node, "Node Type") != "Limit" {
+			return
+		}
+		returned, ok := getFloat(node, "Actual Rows")
+		if !ok {
+			return
+		}
+		children, _ := node["Plans"].([]any)
+		if len(children) == 0 {
+			return
+		}
+		child, _ := children[0].(map[string]any)
+		produced, ok := getFloat(child, "Actual Rows")
+		if !ok {
+			return
+		}
+		discarded = produced - returned
+		found = discarded > 0
+	})
+	return discarded, found
+}
@@ -0,0 +1,271 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type OrPredicateRule struct{}
+
+func NewOrPredicateRule() *OrPredicateRule {
+	return &OrPredicateRule{}
+}
+
+func (r *OrPredicateRule) Name() string {
+	return "OrPredicate"
+}
+
+func (r *OrPredicateRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	root := extractPlanRoot(input.Plan)
+	seen := make(map[string]struct{})
+	plainSelect := isPlainSelect(input.AST)
+
+	walkSelectStmts(input.AST, "query", func(stmt map[string]any, scope string) {
+		walkPredicate(stmt["whereClause"], func(holder map[string]any) {
+			boolExpr, ok := holder["BoolExpr"].(map[string]any)
+			if !ok {
+				return
+			}
+			if boolop, _ := boolExpr["boolop"].(string); boolop != "OR_EXPR" {
+				return
+			}
+			args, _ := boolExpr["args"].([]any)
+			columns, qualifiers, ok := orBranchColumns(args)
+			if !ok {
+				return
+			}
+
+			key := strings.Join(columns, ",")
+			if _, exists := seen[key]; exists {
+				return
+			}
+			seen[key] = struct{}{}
+
+			bitmapOr, scan := orPlanAccess(root, columns)
+			if bitmapOr {
+				return
+			}
+
+			description := fmt.Sprintf("An OR predicate combines conditions on different columns (%s).", strings.Join(columns, ", "))
+			severity := types.SeverityLow
+			if scan != nil {
+				description += fmt.Sprintf(" The plan evaluates it as a filter on %s instead of combining indexes with a BitmapOr.", describePlanNode(scan))
+				severity = types.SeverityMedium
+			}
+
+			recommendation := fmt.Sprintf("Create an index on each of %s so PostgreSQL can combine them with a BitmapOr, or split the predicate into UNION ALL branches that can each use their own index.", strings.Join(columns, ", "))
+			if len(qualifiers) > 1 {
+				description += fmt.Sprintf(" The branches filter different relations (%s), so the OR can only be checked after the join.", strings.Join(qualifiers, ", "))
+				recommendation = "Rewrite the query as a UNION ALL with one branch per condition so each branch can use an index on its own relation before joining."
+			}
+
+			rewrite := ""
+			if plainSelect && scope == "query" {
+				// The index advice stands even when the rewrite cannot be built.
+				if unioned, err := rewriteOrAsUnion(stmt, boolExpr); err == nil {
+					rewrite = unioned
+				}
+			}
+			if rewrite != "" {
+				recommendation += " Later branches exclude rows matched by earlier ones, so the result has no duplicates."
+			}
+
+			suggestions = append(suggestions, types.Suggestion{
+				Title:          "OR predicate across different columns",
+				Description:    description,
+				Recommendation: recommendation,
+				Severity:       severity,
+				Rewrite:        rewrite,
+			})
+		})
+	})
+
+	return suggestions, nil
+}
+
+// orBranchColumns returns the columns referenced by the branches of an OR and
+// the relations they are qualified with. It reports false when every branch
+// tests the same columns, which an IN list or a single index handles.
+func orBranchColumns(args []any) ([]string, []string, bool) {
+	if len(args) < 2 {
+		return nil, nil, false
+	}
+
+	columns := make([]string, 0)
+	qualifiers := make([]string, 0)
+	var first string
+	differs := false
+	for i, arg := range args {
+		branch := make([]string, 0)
+		walkPredicate(arg, func(node map[string]any) {
+			fields, ok := extractColumnRefFields(node)
+			if !ok {
+				return
+			}
+			column := strings.Join(fields, ".")
+			if !slices.Contains(branch, column) {
+				branch = append(branch, column)
+			}
+			if len(fields) > 1 && !slices.Contains(qualifiers, fields[len(fields)-2]) {
+				qualifiers = append(qualifiers, fields[len(fields)-2])
+			}
+		})
+		if len(branch) == 0 {
+			return nil, nil, false
+		}
+		slices.Sort(branch)
+		signature := strings.Join(branch, ",")
+		if i == 0 {
+			first = signature
+		} else if signature != first {
+			differs = true
+		}
+		for _, column := range branch {
+			if !slices.Contains(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+	}
+	return columns, qualifiers, differs
+}
+
+// orPlanAccess reports whether the plan combines indexes with a BitmapOr and
+// otherwise returns the node that filters rows with an OR on the columns.
+func orPlanAccess(root map[string]any, columns []string) (bool, map[string]any) {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column[strings.LastIndex(column, ".")+1:])
+	}
+
+	bitmapOr := false
+	var scan map[string]any
+	traversePlan(root, func(node map[string]any) {
+		if getString(node, "Node Type") == "BitmapOr" {
+			bitmapOr = true
+			return
+		}
+		if scan != nil {
+			return
+		}
+		for _, key := range []string{"Filter", "Join Filter"} {
+			cond := getString(node, key)
+			if !strings.Contains(cond, " OR ") {
+				continue
+			}
+			for _, column := range extractFilterColumns(cond) {
+				if slices.Contains(names, column) {
+					scan = node
+					return
+				}
+			}
+		}
+	})
+	return bitmapOr, scan
+}
+
+func isPlainSelect(ast map[string]any) bool {
+	stmts, _ := ast["stmts"].([]any)
+	if len(stmts) != 1 {
+		return false
+	}
+	stmt, _ := ChildNode(stmts[0], "stmt")
+	_, ok := ChildNode(stmt, "SelectStmt")
+	return ok
+}
+
+// rewriteOrAsUnion turns the OR into one SELECT per branch joined with
+// UNION ALL. It gives up on statements whose ORDER BY, LIMIT, grouping or
+// DISTINCT would have to be applied to the whole union, and on ORs that are
+// not the whole WHERE clause or one of its top-level AND conjuncts.
+func rewriteOrAsUnion(stmt, orExpr map[string]any) (string, error) {
+	if !isTopLevelConjunct(stmt["whereClause"], orExpr) {
+		return "", nil
+	}
+	for _, key := range []string{"sortClause", "limitCount", "limitOffset", "groupClause", "havingClause", "distinctClause", "withClause", "lockingClause"} {
+		if _, ok := stmt[key]; ok {
+			return "", nil
+		}
+	}
+	if op, _ := stmt["op"].(string); op != "" && op != "SETOP_NONE" {
+		return "", nil
+	}
+
+	branches, _ := orExpr["args"].([]any)
+	var union map[string]any
+	for i, branch := range branches {
+		cond, _ := branch.(map[string]any)
+		if i > 0 {
+			var earlier any = branches[0]
+			if i > 1 {
+				earlier = map[string]any{"BoolExpr": map[string]any{"boolop": "OR_EXPR", "args": slices.Clone(branches[:i])}}
+			}
+			cond = map[string]any{"BoolExpr": map[string]any{
+				"boolop": "AND_EXPR",
+				"args": []any{branch, map[string]any{"BooleanTest": map[string]any{
+					"arg":          earlier,
+					"booltesttype": "IS_NOT_TRUE",
+				}}},
+			}}
+		}
+
+		part, err := cloneAST(stmt)
+		if err != nil {
+			return "", err
+		}
+		location := orExpr["location"]
+		walkPredicate(part["whereClause"], func(holder map[string]any) {
+			boolExpr, ok := holder["BoolExpr"].(map[string]any)
+			if !ok || boolExpr["location"] != location {
+				return
+			}
+			if boolop, _ := boolExpr["boolop"].(string); boolop == "OR_EXPR" {
+				delete(holder, "BoolExpr")
+				maps.Copy(holder, cond)
+			}
+		})
+
+		if union == nil {
+			union = part
+			continue
+		}
+		union = map[string]any{
+			"op":   "SETOP_UNION",
+			"all":  true,
+			"larg": union,
+			"rarg": part,
+		}
+	}
+
+	return deparseAST(map[string]any{
+		"stmts": []any{map[string]any{"stmt": map[string]any{"SelectStmt": union}}},
+	})
+}
+
+// isTopLevelConjunct reports whether expr is the WHERE clause itself or a
+// direct argument of its top-level AND, the only places where splitting it
+// into UNION ALL branches keeps the result unchanged.
+func isTopLevelConjunct(where any, expr map[string]any) bool {
+	root, ok := ChildNode(where, "BoolExpr")
+	if !ok {
+		return false
+	}
+	if root["location"] == expr["location"] {
+		return root["boolop"] == expr["boolop"]
+	}
+	if boolop, _ := root["boolop"].(string); boolop != "AND_EXPR" {
+		return false
+	}
+	args, _ := root["args"].([]any)
+	for _, arg := range args {
+		if conjunct, ok := ChildNode(arg, "BoolExpr"); ok && conjunct["location"] == expr["location"] && conjunct["boolop"] == expr["boolop"] {
+			return true
+		}
+	}
+	return false
+}
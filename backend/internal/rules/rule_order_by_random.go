@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type OrderByRandomRule struct{}
+
+func NewOrderByRandomRule() *OrderByRandomRule {
+	return &OrderByRandomRule{}
+}
+
+func (r *OrderByRandomRule) Name() string {
+	return "OrderByRandom"
+}
+
+func (r *OrderByRandomRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	root := extractPlanRoot(input.Plan)
+
+	WalkAST(input.AST, func(node map[string]any) {
+		stmt, ok := node["SelectStmt"].(map[string]any)
+		if !ok || !sortsByRandom(stmt) {
+			return
+		}
+
+		description := "ORDER BY random() assigns a random key to every row and sorts them all, even when only a few rows are returned."
+		traversePlan(root, func(planNode map[string]any) {
+			if getString(planNode, "Node Type") != "Sort" || !strings.Contains(fmt.Sprint(planNode["Sort Key"]), "random()") {
+				return
+			}
+			rows := planNodeRows(planNode)
+			if children, _ := planNode["Plans"].([]any); len(children) == 1 {
+				if child, ok := children[0].(map[string]any); ok {
+					rows = planNodeRows(child)
+				}
+			}
+			description += fmt.Sprintf(" The plan sorts %.0f input rows", rows)
+			if method := getString(planNode, "Sort Method"); method != "" {
+				description += " using " + method
+			}
+			description += "."
+		})
+
+		// The alias is not valid after FROM on its own, so name the table.
+		relation := "the table"
+		if from, _ := stmt["fromClause"].([]any); len(from) == 1 {
+			if rangeVar, ok := ChildNode(from[0], "RangeVar"); ok {
+				relation = QualifiedRelationName(rangeVar)
+			}
+		}
+		recommendation := fmt.Sprintf("For an approximate sample use %s TABLESAMPLE SYSTEM (percent) or BERNOULLI (percent). For a few random rows pick a random key instead: WHERE id >= (SELECT floor(random() * max(id)) FROM %s) ORDER BY id LIMIT n, using the primary key index.", relation, relation)
+		if _, hasLimit := stmt["limitCount"]; !hasLimit {
+			recommendation = "Shuffle the rows in the application if all of them are needed, or sample them with TABLESAMPLE SYSTEM (percent) or BERNOULLI (percent)."
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "ORDER BY random()",
+			Description:    description,
+			Recommendation: recommendation,
+			Severity:       types.SeverityMedium,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func sortsByRandom(stmt map[string]any) bool {
+	sortClause, _ := stmt["sortClause"].([]any)
+	for _, item := range sortClause {
+		sortBy, ok := ChildNode(item, "SortBy")
+		if !ok {
+			continue
+		}
+		if name, _, ok := extractFunctionCall(sortBy["node"]); ok && name == "random" {
+			return true
+		}
+	}
+	return false
+}
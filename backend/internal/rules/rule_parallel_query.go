@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const defaultParallelMinSerialScanCost = 50000
+
+type ParallelQueryRule struct {
+	MinSerialScanCost float64
+}
+
+func NewParallelQueryRule() *ParallelQueryRule {
+	return &ParallelQueryRule{MinSerialScanCost: defaultParallelMinSerialScanCost}
+}
+
+func (r *ParallelQueryRule) Name() string {
+	return "ParallelQuery"
+}
+
+func (r *ParallelQueryRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	root := extractPlanRoot(input.Plan)
+	if root == nil {
+		return nil, nil
+	}
+
+	suggestions := make([]types.Suggestion, 0)
+
+	var visit func(node map[string]any, underGather bool)
+	visit = func(node map[string]any, underGather bool) {
+		nodeType := getString(node, "Node Type")
+		switch {
+		case strings.HasPrefix(nodeType, "Gather"):
+			underGather = true
+			if suggestion, ok := gatherWorkersSuggestion(node); ok {
+				suggestions = append(suggestions, suggestion)
+			}
+		case nodeType == "Seq Scan" && !underGather:
+			parallelAware, _ := node["Parallel Aware"].(bool)
+			cost, _ := getFloat(node, "Total Cost")
+			if !parallelAware && cost >= r.MinSerialScanCost {
+				suggestions = append(suggestions, types.Suggestion{
+					Title:       "Large sequential scan ran without parallel workers",
+					Description: fmt.Sprintf("%s has a total cost of %.0f but was planned as a single-process scan.", describePlanNode(node), cost),
+					Recommendation: "Check that max_parallel_workers_per_gather is above 0 and that the table's parallel_workers storage option is not 0. " +
+						"Functions marked PARALLEL UNSAFE, cursors and writes in the same statement also prevent parallel plans.",
+					Severity: types.SeverityLow,
+				})
+			}
+		}
+
+		children, _ := node["Plans"].([]any)
+		for _, child := range children {
+			if childNode, ok := child.(map[string]any); ok {
+				visit(childNode, underGather)
+			}
+		}
+	}
+	visit(root, false)
+
+	return suggestions, nil
+}
+
+func gatherWorkersSuggestion(node map[string]any) (types.Suggestion, bool) {
+	planned, ok := getFloat(node, "Workers Planned")
+	if !ok {
+		return types.Suggestion{}, false
+	}
+	launched, ok := getFloat(node, "Workers Launched")
+	if !ok || launched >= planned {
+		return types.Suggestion{}, false
+	}
+
+	severity := types.SeverityMedium
+	description := fmt.Sprintf("%s planned %.0f parallel workers but only %.0f were launched, so the leader did the remaining share of the work itself.",
+		getString(node, "Node Type"), planned, launched)
+	if launched == 0 {
+		severity = types.SeverityHigh
+		description = fmt.Sprintf("%s planned %.0f parallel workers but none were launched, so a plan costed for parallel execution ran serially.",
+			getString(node, "Node Type"), planned)
+	}
+
+	return types.Suggestion{
+		Title:       "Parallel workers not available",
+		Description: description,
+		Recommendation: "The server ran out of background workers when the query started. Raise max_parallel_workers and max_worker_processes " +
+			"(the latter needs a restart), or lower max_parallel_workers_per_gather so concurrent queries share the pool predictably.",
+		Severity: severity,
+	}, true
+}
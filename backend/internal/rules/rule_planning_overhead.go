@@ -0,0 +1,100 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultPlanningMinTime  = 5
+	defaultPlanningMinShare = 0.5
+	defaultJITMinTime       = 20
+	defaultJITMinShare      = 0.3
+	manyPartitionScans      = 32
+)
+
+type PlanningOverheadRule struct {
+	MinPlanningTime  float64
+	MinPlanningShare float64
+	MinJITTime       float64
+	MinJITShare      float64
+}
+
+func NewPlanningOverheadRule() *PlanningOverheadRule {
+	return &PlanningOverheadRule{
+		MinPlanningTime:  defaultPlanningMinTime,
+		MinPlanningShare: defaultPlanningMinShare,
+		MinJITTime:       defaultJITMinTime,
+		MinJITShare:      defaultJITMinShare,
+	}
+}
+
+func (r *PlanningOverheadRule) Name() string {
+	return "PlanningOverhead"
+}
+
+func (r *PlanningOverheadRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	if input.Plan == nil {
+		return nil, nil
+	}
+
+	suggestions := make([]types.Suggestion, 0)
+	planning, hasPlanning := getFloat(input.Plan, "Planning Time")
+	execution, hasExecution := getFloat(input.Plan, "Execution Time")
+
+	if hasPlanning && hasExecution && planning >= r.MinPlanningTime && planning/(planning+execution) >= r.MinPlanningShare {
+		recommendation := "Use prepared statements (or your driver's statement cache) so the plan is reused; with a generic plan the planning cost is paid once per session."
+		if scans, pruned := partitionScanCounts(extractPlanRoot(input.Plan)); scans >= manyPartitionScans || pruned > 0 {
+			recommendation += fmt.Sprintf(" The plan spans %d partition scans", scans)
+			if pruned > 0 {
+				recommendation += fmt.Sprintf(" after pruning %d more at run time", pruned)
+			}
+			recommendation += "; use fewer, larger partitions or filter on the partition key with constants so pruning happens at plan time."
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Planning dominates query time",
+			Description:    fmt.Sprintf("Planning took %.2f ms while execution took %.2f ms (%.0f%% of the total spent planning).", planning, execution, planning/(planning+execution)*100),
+			Recommendation: recommendation,
+			Severity:       types.SeverityMedium,
+		})
+	}
+
+	jit, _ := input.Plan["JIT"].(map[string]any)
+	timing, _ := jit["Timing"].(map[string]any)
+	jitTotal, hasJIT := getFloat(timing, "Total")
+	if hasJIT && hasExecution && execution > 0 && jitTotal >= r.MinJITTime && jitTotal/execution >= r.MinJITShare {
+		functions, _ := getFloat(jit, "Functions")
+		suggestions = append(suggestions, types.Suggestion{
+			Title: "JIT compilation overhead",
+			Description: fmt.Sprintf("JIT compiled %.0f functions in %.2f ms, %.0f%% of the %.2f ms execution time.",
+				functions, jitTotal, jitTotal/execution*100, execution),
+			Recommendation: "JIT only pays off for long-running analytical queries. Raise jit_above_cost (and jit_inline_above_cost, jit_optimize_above_cost) above this query's cost, or SET jit = off for this workload.",
+			Severity:       types.SeverityMedium,
+		})
+	}
+
+	return suggestions, nil
+}
+
+func partitionScanCounts(root map[string]any) (int, int) {
+	scans, pruned := 0, 0
+	traversePlan(root, func(node map[string]any) {
+		nodeType := getString(node, "Node Type")
+		if nodeType != "Append" && nodeType != "Merge Append" {
+			return
+		}
+		if removed, ok := getFloat(node, "Subplans Removed"); ok {
+			pruned += int(removed)
+		}
+		children, _ := node["Plans"].([]any)
+		for _, child := range children {
+			if childNode, ok := child.(map[string]any); ok && getString(childNode, "Relation Name") != "" {
+				scans++
+			}
+		}
+	})
+	return scans, pruned
+}
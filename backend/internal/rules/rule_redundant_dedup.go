@@ -0,0 +1,214 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type RedundantDedupRule struct{}
+
+func NewRedundantDedupRule() *RedundantDedupRule {
+	return &RedundantDedupRule{}
+}
+
+func (r *RedundantDedupRule) Name() string {
+	return "RedundantDedup"
+}
+
+func (r *RedundantDedupRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	root := extractPlanRoot(input.Plan)
+
+	reportedUnion := false
+	WalkAST(input.AST, func(node map[string]any) {
+		stmt, ok := node["SelectStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+
+		if op, _ := stmt["op"].(string); op == "SETOP_UNION" && !reportedUnion {
+			if all, _ := stmt["all"].(bool); !all {
+				reportedUnion = true
+				description := "UNION removes duplicate rows across its branches, which needs a sort or hash over the combined result."
+				severity := types.SeverityLow
+				if dedup, ok := findDedupNode(root, func(child map[string]any) bool {
+					return getString(child, "Node Type") == "Append"
+				}); ok {
+					description += " " + dedup.describe()
+					severity = types.SeverityMedium
+				}
+				suggestions = append(suggestions, types.Suggestion{
+					Title:          "UNION instead of UNION ALL",
+					Description:    description,
+					Recommendation: "Use UNION ALL when the branches cannot return the same row or duplicates are acceptable; it simply appends the results.",
+					Severity:       severity,
+				})
+			}
+		}
+
+		if !isPlainDistinct(stmt) || !hasJoin(stmt) {
+			return
+		}
+		description := "SELECT DISTINCT over a join usually hides rows multiplied by a one-to-many relationship."
+		severity := types.SeverityLow
+		if dedup, ok := findDedupNode(root, func(child map[string]any) bool {
+			joined := false
+			traversePlan(child, func(inner map[string]any) {
+				switch getString(inner, "Node Type") {
+				case "Nested Loop", "Hash Join", "Merge Join":
+					joined = true
+				}
+			})
+			return joined
+		}); ok {
+			description += " " + dedup.describe()
+			severity = types.SeverityMedium
+		}
+
+		recommendation := "Remove DISTINCT if the join cannot produce duplicates, or replace the join with EXISTS (a semi-join) when the joined relation is only used for filtering."
+		if qualifier, ok := singleTargetQualifier(stmt); ok {
+			recommendation = fmt.Sprintf("Only columns of %s are selected, so move the other relations into WHERE EXISTS (...); the semi-join returns each %s row once and DISTINCT can be dropped.", qualifier, qualifier)
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "DISTINCT used to remove join duplicates",
+			Description:    description,
+			Recommendation: recommendation,
+			Severity:       severity,
+		})
+	})
+
+	return suggestions, nil
+}
+
+type dedupNode struct {
+	name     string
+	overSort bool
+	cost     float64
+	time     float64
+	hasTime  bool
+}
+
+func (d dedupNode) describe() string {
+	description := fmt.Sprintf("The plan deduplicates with a %s", d.name)
+	if d.overSort {
+		description += " over a Sort"
+	}
+	description += fmt.Sprintf(", costing %.0f units", d.cost)
+	if d.hasTime {
+		description += fmt.Sprintf(" and %.2f ms", d.time)
+	}
+	return description + "."
+}
+
+// findDedupNode returns the first Unique or hashed/sorted aggregate whose
+// input matches source, with the cost and time it adds on top of that input.
+func findDedupNode(root map[string]any, source func(map[string]any) bool) (dedupNode, bool) {
+	var result dedupNode
+	found := false
+	traversePlan(root, func(node map[string]any) {
+		if found {
+			return
+		}
+		name, ok := dedupNodeName(node)
+		if !ok {
+			return
+		}
+		children, _ := node["Plans"].([]any)
+		if len(children) != 1 {
+			return
+		}
+		input, _ := children[0].(map[string]any)
+		overSort := false
+		if getString(input, "Node Type") == "Sort" {
+			sortChildren, _ := input["Plans"].([]any)
+			if len(sortChildren) == 1 {
+				if sortInput, ok := sortChildren[0].(map[string]any); ok {
+					input, overSort = sortInput, true
+				}
+			}
+		}
+		if input == nil || !source(input) {
+			return
+		}
+
+		totalCost, _ := getFloat(node, "Total Cost")
+		inputCost, _ := getFloat(input, "Total Cost")
+		result = dedupNode{name: name, overSort: overSort, cost: totalCost - inputCost}
+		if totalTime, ok := getFloat(node, "Actual Total Time"); ok {
+			inputTime, _ := getFloat(input, "Actual Total Time")
+			result.time, result.hasTime = totalTime-inputTime, true
+		}
+		found = true
+	})
+	return result, found
+}
+
+// dedupNodeName names the nodes that remove duplicates the way EXPLAIN's text
+// format does. JSON plans report every aggregate as "Aggregate" with a
+// strategy; plain and mixed aggregates do not deduplicate rows.
+func dedupNodeName(node map[string]any) (string, bool) {
+	nodeType := getString(node, "Node Type")
+	switch nodeType {
+	case "Unique", "HashAggregate", "GroupAggregate":
+		return nodeType, true
+	case "Aggregate":
+		switch getString(node, "Strategy") {
+		case "Hashed":
+			return "HashAggregate", true
+		case "Sorted":
+			return "GroupAggregate", true
+		}
+	}
+	return "", false
+}
+
+func isPlainDistinct(stmt map[string]any) bool {
+	distinct, _ := stmt["distinctClause"].([]any)
+	if len(distinct) == 0 {
+		return false
+	}
+	for _, item := range distinct {
+		// DISTINCT ON lists expressions; plain DISTINCT has a single empty node.
+		if m, ok := item.(map[string]any); !ok || len(m) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func hasJoin(stmt map[string]any) bool {
+	from, _ := stmt["fromClause"].([]any)
+	if len(from) > 1 {
+		return true
+	}
+	for _, item := range from {
+		if _, ok := ChildNode(item, "JoinExpr"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func singleTargetQualifier(stmt map[string]any) (string, bool) {
+	targets, _ := stmt["targetList"].([]any)
+	qualifier := ""
+	for _, target := range targets {
+		resTarget, ok := ChildNode(target, "ResTarget")
+		if !ok {
+			return "", false
+		}
+		fields, ok := extractColumnRefFields(resTarget["val"])
+		if !ok || len(fields) < 2 {
+			return "", false
+		}
+		name := fields[len(fields)-2]
+		if qualifier != "" && name != qualifier {
+			return "", false
+		}
+		qualifier = name
+	}
+	return qualifier, qualifier != ""
+}
@@ -0,0 +1,86 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const defaultRenameHotActivity = 10000
+
+const tableActivityQuery = `SELECT coalesce(seq_scan, 0) + coalesce(idx_scan, 0), n_tup_ins + n_tup_upd + n_tup_del
+FROM pg_stat_user_tables
+WHERE relname = $1
+ORDER BY schemaname = current_schema() DESC
+LIMIT 1`
+
+type RenameObjectRule struct {
+	HotActivity int64
+}
+
+func NewRenameObjectRule() *RenameObjectRule {
+	return &RenameObjectRule{HotActivity: defaultRenameHotActivity}
+}
+
+func (r *RenameObjectRule) Name() string {
+	return "RenameObject"
+}
+
+func (r *RenameObjectRule) Apply(ctx context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+
+	WalkAST(input.AST, func(node map[string]any) {
+		stmt, ok := node["RenameStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		renameType, _ := stmt["renameType"].(string)
+		if renameType != "OBJECT_TABLE" && renameType != "OBJECT_COLUMN" {
+			return
+		}
+
+		relation, _ := stmt["relation"].(map[string]any)
+		table := QualifiedRelationName(relation)
+		newName, _ := stmt["newname"].(string)
+
+		description := fmt.Sprintf("Renaming table %s to %s", table, newName)
+		recommendation := fmt.Sprintf("Deploy in steps: rename the table, create a view named %s selecting from %s so old code keeps working, switch the application to %s, then drop the view.", table, newName, newName)
+		if renameType == "OBJECT_COLUMN" {
+			column, _ := stmt["subname"].(string)
+			description = fmt.Sprintf("Renaming column %s.%s to %s", table, column, newName)
+			recommendation = fmt.Sprintf("Deploy in steps: add %s, keep both columns in sync with a trigger and backfill it, switch the application to the new name, then drop %s.", newName, column)
+		}
+		description += " takes a brief AccessExclusiveLock, and every query or prepared statement still using the old name fails as soon as it commits."
+
+		severity := types.SeverityMedium
+		relname, _ := relation["relname"].(string)
+		if scans, writes, ok := loadTableActivity(ctx, input.DB, relname); ok {
+			description += fmt.Sprintf(" pg_stat_user_tables reports %d scans and %d written rows on %s.", scans, writes, table)
+			if scans+writes >= r.HotActivity {
+				severity = types.SeverityHigh
+			}
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Rename breaks running application code",
+			Description:    description,
+			Recommendation: recommendation + " " + lockTimeoutAdvice,
+			Severity:       severity,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func loadTableActivity(ctx context.Context, db Querier, relation string) (int64, int64, bool) {
+	if db == nil || relation == "" {
+		return 0, 0, false
+	}
+
+	var scans, writes int64
+	if err := db.QueryRow(ctx, tableActivityQuery, relation).Scan(&scans, &writes); err != nil {
+		return 0, 0, false
+	}
+	return scans, writes, true
+}
@@ -0,0 +1,115 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultRowEstimateFactor  = 10
+	defaultRowEstimateMinRows = 1000
+	rowEstimateHighFactor     = 100
+)
+
+type RowEstimateRule struct {
+	Factor  float64
+	MinRows float64
+}
+
+func NewRowEstimateRule() *RowEstimateRule {
+	return &RowEstimateRule{
+		Factor:  defaultRowEstimateFactor,
+		MinRows: defaultRowEstimateMinRows,
+	}
+}
+
+func (r *RowEstimateRule) Name() string {
+	return "RowEstimate"
+}
+
+func (r *RowEstimateRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	root := extractPlanRoot(input.Plan)
+	if root == nil {
+		return nil, nil
+	}
+
+	suggestions := make([]types.Suggestion, 0)
+	traversePlan(root, func(node map[string]any) {
+		planRows, ok := getFloat(node, "Plan Rows")
+		if !ok {
+			return
+		}
+		actualRows, ok := getFloat(node, "Actual Rows")
+		if !ok {
+			return
+		}
+		loops, ok := getFloat(node, "Actual Loops")
+		if !ok || loops == 0 {
+			// Never executed: nothing was observed to compare against.
+			return
+		}
+
+		// Both "Plan Rows" and "Actual Rows" are per-loop figures, so the
+		// totals are scaled by the loop count before comparing them.
+		estimated := planRows * loops
+		actual := actualRows * loops
+		if math.Max(estimated, actual) < r.MinRows {
+			return
+		}
+
+		ratio := math.Max(estimated, 1) / math.Max(actual, 1)
+		direction := "overestimated"
+		if ratio < 1 {
+			ratio = 1 / ratio
+			direction = "underestimated"
+		}
+		if ratio < r.Factor {
+			return
+		}
+
+		severity := types.SeverityMedium
+		if ratio >= rowEstimateHighFactor {
+			severity = types.SeverityHigh
+		}
+
+		description := fmt.Sprintf("%s %s its row count %.0fx: estimated %.0f rows, observed %.0f over %.0f loop(s).",
+			describePlanNode(node), direction, ratio, estimated, actual, loops)
+		if cond := planCondition(node); cond != "" {
+			description += fmt.Sprintf(" The estimate comes from condition %s.", cond)
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Row count misestimate",
+			Description:    description,
+			Recommendation: rowEstimateRecommendation(node),
+			Severity:       severity,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func rowEstimateRecommendation(node map[string]any) string {
+	nodeType := getString(node, "Node Type")
+	relation := getString(node, "Relation Name")
+	cond := planCondition(node)
+
+	switch {
+	case relation != "" && strings.Contains(strings.ToUpper(cond), " AND "):
+		return fmt.Sprintf("The condition combines several columns of %q that are probably correlated. Run ANALYZE %s and run CREATE STATISTICS (dependencies) ON <col1>, <col2> FROM %s so the planner stops multiplying their selectivities.",
+			relation, relation, relation)
+	case relation != "":
+		return fmt.Sprintf("Run ANALYZE %s to refresh its statistics. If the estimate stays off, raise the sample size with ALTER TABLE %s ALTER COLUMN <column> SET STATISTICS 1000 for the filtered column and analyze again.",
+			relation, relation)
+	case nodeType == "Hash Join" || nodeType == "Merge Join" || nodeType == "Nested Loop":
+		return "Join estimates depend on the distinct counts of the join keys. Run ANALYZE on both joined tables, and if the keys span several columns run CREATE STATISTICS (ndistinct, dependencies) on them."
+	case nodeType == "Aggregate":
+		return "Group count estimates rely on per-column distinct values. Run CREATE STATISTICS (ndistinct) on the grouping columns and run ANALYZE on the underlying table."
+	default:
+		return "Refresh statistics with ANALYZE on the tables below this node and, if the misestimate persists, raise the statistics target of the columns used in its conditions."
+	}
+}
@@ -0,0 +1,278 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type SelectStarRule struct{}
+
+func NewSelectStarRule() *SelectStarRule {
+	return &SelectStarRule{}
+}
+
+func (r *SelectStarRule) Name() string {
+	return "SelectStar"
+}
+
+func (r *SelectStarRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+	root := extractPlanRoot(input.Plan)
+
+	narrowedCTEs := make(map[string]struct{})
+	WalkAST(input.AST, func(node map[string]any) {
+		stmt, ok := node["SelectStmt"].(map[string]any)
+		if !ok {
+			return
+		}
+		for _, cte := range starCTEs(stmt) {
+			used, ok := cteColumnsUsed(stmt, cte)
+			if !ok || len(used) == 0 {
+				continue
+			}
+			narrowedCTEs[cte] = struct{}{}
+
+			description := fmt.Sprintf("CTE %s selects * but the outer query only uses %s.", cte, strings.Join(used, ", "))
+			if width, rows, ok := cteWidth(root, cte); ok {
+				description += fmt.Sprintf(" Each of its %.0f rows is %.0f bytes wide (%s in total).", rows, width, formatBytes(width*rows))
+				if read, ok := cteReadWidth(root, cte); ok && read < width {
+					wasted := width - read
+					description += fmt.Sprintf(" The outer query reads %.0f bytes of each row, so %.0f bytes per row (%s) are materialized but never read.", read, wasted, formatBytes(wasted*rows))
+				}
+			}
+
+			suggestions = append(suggestions, types.Suggestion{
+				Title:          "CTE selects unused columns",
+				Description:    description,
+				Recommendation: fmt.Sprintf("List only %s in CTE %s so it carries narrower rows.", strings.Join(used, ", "), cte),
+				Severity:       types.SeverityMedium,
+			})
+		}
+	})
+
+	seen := make(map[string]struct{})
+	walkSelectStmts(input.AST, "query", func(stmt map[string]any, scope string) {
+		qualifier, ok := targetListStar(stmt)
+		if !ok {
+			return
+		}
+		if name, isCTE := strings.CutPrefix(scope, "CTE "); isCTE {
+			if _, exists := narrowedCTEs[name]; exists {
+				return
+			}
+		}
+
+		key := scope + ":" + qualifier
+		if _, exists := seen[key]; exists {
+			return
+		}
+		seen[key] = struct{}{}
+
+		projection := "*"
+		if qualifier != "" {
+			projection = qualifier + ".*"
+		}
+		description := fmt.Sprintf("SELECT %s in the %s returns every column, including ones the caller may not need.", projection, scope)
+		if width, rows, ok := scopeWidth(root, scope); ok {
+			description += fmt.Sprintf(" Rows are %.0f bytes wide (Plan Width), about %s for %.0f rows.", width, formatBytes(width*rows), rows)
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Wide SELECT * projection",
+			Description:    description,
+			Recommendation: "List the needed columns explicitly; narrower rows reduce I/O, memory and network transfer and allow index-only scans.",
+			Severity:       types.SeverityLow,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func targetListStar(stmt map[string]any) (string, bool) {
+	targets, _ := stmt["targetList"].([]any)
+	for _, target := range targets {
+		resTarget, ok := ChildNode(target, "ResTarget")
+		if !ok {
+			continue
+		}
+		fields, ok := extractColumnRefFields(resTarget["val"])
+		if !ok || fields[len(fields)-1] != "*" {
+			continue
+		}
+		if len(fields) > 1 {
+			return fields[len(fields)-2], true
+		}
+		return "", true
+	}
+	return "", false
+}
+
+func starCTEs(stmt map[string]any) []string {
+	withClause, ok := stmt["withClause"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	names := make([]string, 0)
+	ctes, _ := withClause["ctes"].([]any)
+	for _, raw := range ctes {
+		cte, ok := ChildNode(raw, "CommonTableExpr")
+		if !ok {
+			continue
+		}
+		query, ok := ChildNode(cte["ctequery"], "SelectStmt")
+		if !ok {
+			continue
+		}
+		if _, ok := targetListStar(query); ok {
+			name, _ := cte["ctename"].(string)
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func cteColumnsUsed(stmt map[string]any, cte string) ([]string, bool) {
+	aliases := make(map[string]struct{})
+	relations := 0
+	refs := make([][]string, 0)
+
+	keys := make([]string, 0, len(stmt))
+	for key := range stmt {
+		if key != "withClause" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		WalkAST(stmt[key], func(node map[string]any) {
+			if rangeVar, ok := node["RangeVar"].(map[string]any); ok {
+				relations++
+				relname, _ := rangeVar["relname"].(string)
+				if _, hasSchema := rangeVar["schemaname"]; relname == cte && !hasSchema {
+					alias := relname
+					if aliasNode, ok := rangeVar["alias"].(map[string]any); ok {
+						alias, _ = aliasNode["aliasname"].(string)
+					}
+					aliases[alias] = struct{}{}
+				}
+			}
+			if _, ok := node["RangeSubselect"]; ok {
+				relations++
+			}
+			if fields, ok := extractColumnRefFields(node); ok {
+				refs = append(refs, fields)
+			}
+		})
+	}
+
+	if len(aliases) == 0 {
+		return nil, false
+	}
+
+	used := make([]string, 0)
+	for _, fields := range refs {
+		var column string
+		switch {
+		case len(fields) == 1 && relations == 1:
+			column = fields[0]
+		case len(fields) == 1:
+			// An unqualified column may belong to the CTE, so the list of
+			// used columns would be incomplete.
+			return nil, false
+		case len(fields) >= 2:
+			if _, ok := aliases[fields[len(fields)-2]]; !ok {
+				continue
+			}
+			column = fields[len(fields)-1]
+		default:
+			continue
+		}
+		if column == "*" {
+			return nil, false
+		}
+		if !slices.Contains(used, column) {
+			used = append(used, column)
+		}
+	}
+	sort.Strings(used)
+	return used, true
+}
+
+// cteWidth returns the row width and row count of the materialized CTE,
+// falling back to its CTE Scan when the plan has no separate CTE subplan.
+func cteWidth(root map[string]any, cte string) (float64, float64, bool) {
+	var width, rows float64
+	found, materialized := false, false
+	traversePlan(root, func(node map[string]any) {
+		if materialized {
+			return
+		}
+		isSubplan := getString(node, "Subplan Name") == "CTE "+cte
+		if isSubplan || (!found && getString(node, "CTE Name") == cte) {
+			width, found = getFloat(node, "Plan Width")
+			rows = planNodeRows(node)
+			materialized = found && isSubplan
+		}
+	})
+	return width, rows, found
+}
+
+// cteReadWidth returns the widest row any CTE Scan of the CTE projects,
+// which is the part of each materialized row the outer query uses.
+func cteReadWidth(root map[string]any, cte string) (float64, bool) {
+	var width float64
+	found := false
+	traversePlan(root, func(node map[string]any) {
+		if getString(node, "Node Type") != "CTE Scan" || getString(node, "CTE Name") != cte {
+			return
+		}
+		if scanned, ok := getFloat(node, "Plan Width"); ok && (!found || scanned > width) {
+			width, found = scanned, true
+		}
+	})
+	return width, found
+}
+
+func scopeWidth(root map[string]any, scope string) (float64, float64, bool) {
+	if root == nil {
+		return 0, 0, false
+	}
+	if scope == "query" {
+		width, ok := getFloat(root, "Plan Width")
+		return width, planNodeRows(root), ok
+	}
+	if name, ok := strings.CutPrefix(scope, "CTE "); ok {
+		return cteWidth(root, name)
+	}
+	if alias, ok := strings.CutPrefix(scope, "subquery "); ok {
+		var width, rows float64
+		found := false
+		traversePlan(root, func(node map[string]any) {
+			if !found && getString(node, "Node Type") == "Subquery Scan" && getString(node, "Alias") == alias {
+				width, found = getFloat(node, "Plan Width")
+				rows = planNodeRows(node)
+			}
+		})
+		return width, rows, found
+	}
+	return 0, 0, false
+}
+
+func planNodeRows(node map[string]any) float64 {
+	if rows, ok := getFloat(node, "Actual Rows"); ok {
+		loops, ok := getFloat(node, "Actual Loops")
+		if !ok {
+			loops = 1
+		}
+		return rows * loops
+	}
+	rows, _ := getFloat(node, "Plan Rows")
+	return rows
+}
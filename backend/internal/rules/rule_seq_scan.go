@@ -7,10 +7,27 @@ import (
 	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
 )
 
-type SeqScanRule struct{}
+const (
+	defaultSeqScanMinRows         = 10000
+	defaultSeqScanMinCost         = 1000
+	defaultSeqScanMinTimeShare    = 0.2
+	defaultSeqScanMinRemovedShare = 0.9
+)
+
+type SeqScanRule struct {
+	MinRows         float64
+	MinCost         float64
+	MinTimeShare    float64
+	MinRemovedShare float64
+}
 
 func NewSeqScanRule() *SeqScanRule {
-	return &SeqScanRule{}
+	return &SeqScanRule{
+		MinRows:         defaultSeqScanMinRows,
+		MinCost:         defaultSeqScanMinCost,
+		MinTimeShare:    defaultSeqScanMinTimeShare,
+		MinRemovedShare: defaultSeqScanMinRemovedShare,
+	}
 }
 
 func (r *SeqScanRule) Name() string {
@@ -23,24 +40,121 @@ func (r *SeqScanRule) Apply(_ context.Context, input Input) ([]types.Suggestion,
 		return nil, nil
 	}
 
+	executionTime, _ := getFloat(input.Plan, "Execution Time")
+
 	suggestions := make([]types.Suggestion, 0)
 	traversePlan(root, func(node map[string]any) {
-		if getString(node, "Node Type") == "Seq Scan" {
-			relation := getString(node, "Relation Name")
-			if relation == "" {
-				relation = "target table"
-			}
+		if getString(node, "Node Type") != "Seq Scan" {
+			return
+		}
+
+		relation := getString(node, "Relation Name")
+		if relation == "" {
+			relation = "target table"
+		}
+
+		stats, ok := collectSeqScanStats(node, executionTime)
+		if !ok {
+			// Without row counts or costs there is nothing to weigh the scan
+			// against, so keep reporting it the conservative way.
 			suggestions = append(suggestions, types.Suggestion{
 				Title:          "Sequential scan detected",
 				Description:    fmt.Sprintf("The query plan uses a sequential scan on %q.", relation),
 				Recommendation: fmt.Sprintf("Consider adding an appropriate index on %q or rewriting the filter to enable index usage.", relation),
 				Severity:       types.SeverityHigh,
 			})
+			return
+		}
+
+		large := stats.scanned >= r.MinRows
+		expensive := stats.cost >= r.MinCost || stats.timeShare >= r.MinTimeShare
+		if !large && !expensive {
+			return
+		}
+
+		description := fmt.Sprintf("The query plan uses a sequential scan on %q reading about %.0f rows (cost %.0f", relation, stats.scanned, stats.cost)
+		if stats.estimated {
+			// Plan Rows counts the rows left after the filter, not the rows read.
+			description = fmt.Sprintf("The query plan uses a sequential scan on %q with an estimated output of %.0f rows (cost %.0f", relation, stats.scanned, stats.cost)
+		}
+		if stats.timeShare > 0 {
+			description += fmt.Sprintf(", %.0f%% of execution time", stats.timeShare*100)
+		}
+		description += ")."
+
+		selective := stats.hasRemoved && stats.removedShare >= r.MinRemovedShare
+		if expensive && selective {
+			suggestions = append(suggestions, types.Suggestion{
+				Title:          "Expensive selective sequential scan",
+				Description:    description + fmt.Sprintf(" The filter discards %.0f%% of the rows it reads.", stats.removedShare*100),
+				Recommendation: fmt.Sprintf("Add an index on %q covering the filtered columns so only matching rows are read.", relation),
+				Severity:       types.SeverityHigh,
+			})
+			return
+		}
+
+		recommendation := fmt.Sprintf("The scan keeps most rows of %q, so an index would not help much; reduce the rows read or accept the full scan.", relation)
+		switch {
+		case getString(node, "Filter") == "":
+			recommendation = fmt.Sprintf("The scan has no filter and reads all of %q; an index would only help if the query restricted the rows it needs.", relation)
+		case !stats.hasRemoved:
+			recommendation = fmt.Sprintf("Check whether the filter on %q is selective; an index only pays off when it skips most of the table.", relation)
 		}
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Sequential scan detected",
+			Description:    description,
+			Recommendation: recommendation,
+			Severity:       types.SeverityLow,
+		})
 	})
 
 	return suggestions, nil
 }
 
+type seqScanStats struct {
+	scanned      float64
+	cost         float64
+	timeShare    float64
+	removedShare float64
+	hasRemoved   bool
+	// estimated is set when scanned is the planner's output estimate
+	// because the plan has no actual row counts.
+	estimated bool
+}
+
+func collectSeqScanStats(node map[string]any, executionTime float64) (seqScanStats, bool) {
+	var stats seqScanStats
+
+	planRows, hasPlanRows := getFloat(node, "Plan Rows")
+	actualRows, hasActualRows := getFloat(node, "Actual Rows")
+	cost, hasCost := getFloat(node, "Total Cost")
+	if !hasPlanRows && !hasActualRows && !hasCost {
+		return stats, false
+	}
+	stats.cost = cost
+
+	loops, ok := getFloat(node, "Actual Loops")
+	if !ok || loops == 0 {
+		loops = 1
+	}
+
+	removed, hasRemoved := getFloat(node, "Rows Removed by Filter")
+	if hasActualRows {
+		// Like "Actual Rows", the removed count is a per-loop average.
+		perLoop := actualRows + removed
+		stats.scanned = perLoop * loops
+		if hasRemoved && perLoop > 0 {
+			stats.hasRemoved = true
+			stats.removedShare = removed / perLoop
+		}
+	} else {
+		stats.scanned = planRows
+		stats.estimated = true
+	}
 
+	if totalTime, ok := getFloat(node, "Actual Total Time"); ok && executionTime > 0 {
+		stats.timeShare = totalTime * loops / executionTime
+	}
 
+	return stats, true
+}
@@ -0,0 +1,136 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+const (
+	defaultTriggerMinTime  = 10
+	defaultTriggerMinShare = 0.3
+	triggerHighShare       = 0.5
+)
+
+// foreignKeyIndexQuery resolves a foreign key to its referencing table and
+// columns and reports whether an index leads with those columns. Constraint
+// names are only unique per table, so the key is also matched on the
+// referenced table the action trigger fired on.
+const foreignKeyIndexQuery = `SELECT c.conrelid::regclass::text,
+       string_agg(a.attname, ', ' ORDER BY k.ord),
+       EXISTS (
+           SELECT 1
+           FROM pg_index i
+           WHERE i.indrelid = c.conrelid
+             AND i.indpred IS NULL
+             AND (i.indkey::int2[])[0:cardinality(c.conkey) - 1] @> c.conkey
+       )
+FROM pg_constraint c
+CROSS JOIN LATERAL unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord)
+JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+WHERE c.conname = $1 AND c.confrelid = $2::regclass AND c.contype = 'f'
+GROUP BY c.oid, c.conrelid, c.conkey
+LIMIT 1`
+
+type TriggerOverheadRule struct {
+	MinTime  float64
+	MinShare float64
+}
+
+func NewTriggerOverheadRule() *TriggerOverheadRule {
+	return &TriggerOverheadRule{
+		MinTime:  defaultTriggerMinTime,
+		MinShare: defaultTriggerMinShare,
+	}
+}
+
+func (r *TriggerOverheadRule) Name() string {
+	return "TriggerOverhead"
+}
+
+type foreignKeyIndex struct {
+	table   string
+	columns string
+	indexed bool
+}
+
+func (r *TriggerOverheadRule) Apply(ctx context.Context, input Input) ([]types.Suggestion, error) {
+	triggers, _ := input.Plan["Triggers"].([]any)
+	executionTime, _ := getFloat(input.Plan, "Execution Time")
+	if len(triggers) == 0 || executionTime <= 0 {
+		return nil, nil
+	}
+
+	suggestions := make([]types.Suggestion, 0)
+	for _, raw := range triggers {
+		trigger, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		elapsed, _ := getFloat(trigger, "Time")
+		share := elapsed / executionTime
+		if elapsed < r.MinTime || share < r.MinShare {
+			continue
+		}
+
+		name := getString(trigger, "Trigger Name")
+		constraint := getString(trigger, "Constraint Name")
+		relation := getString(trigger, "Relation")
+		calls, _ := getFloat(trigger, "Calls")
+
+		subject := fmt.Sprintf("Trigger %s on %s", name, relation)
+		if constraint != "" {
+			subject = fmt.Sprintf("Foreign key %s checked on %s", constraint, relation)
+		}
+		description := fmt.Sprintf("%s ran %.0f times for %.2f ms, %.0f%% of the %.2f ms execution time.", subject, calls, elapsed, share*100, executionTime)
+
+		severity := types.SeverityMedium
+		if share >= triggerHighShare {
+			severity = types.SeverityHigh
+		}
+
+		var recommendation string
+		switch {
+		case constraint != "" && strings.HasPrefix(name, "RI_ConstraintTrigger_a_"):
+			// Action triggers fire on the referenced table and look up the
+			// referencing rows, which is a sequential scan without an index.
+			recommendation = fmt.Sprintf("Every changed %s row looks up its referencing rows of %s. Make sure the referencing columns are indexed.", relation, constraint)
+			if fk, ok := loadForeignKeyIndex(ctx, input.DB, constraint, relation); ok {
+				if fk.indexed {
+					recommendation = fmt.Sprintf("The referencing columns %s(%s) are indexed; batch the change or check the index is not bloated.", fk.table, fk.columns)
+				} else {
+					description += fmt.Sprintf(" %s(%s) has no index on the referencing columns, so each check scans %s.", fk.table, fk.columns, fk.table)
+					recommendation = fmt.Sprintf("Run CREATE INDEX CONCURRENTLY ON %s (%s) so each check becomes an index lookup instead of a scan.", fk.table, fk.columns)
+					severity = types.SeverityHigh
+				}
+			}
+		case constraint != "":
+			recommendation = "Each inserted or updated row checks the referenced key separately. For bulk loads, load into a staging table and insert with a single statement, or validate the constraint afterwards (NOT VALID, then VALIDATE CONSTRAINT)."
+		default:
+			recommendation = fmt.Sprintf("Move the work of %s into a statement-level trigger with transition tables (REFERENCING NEW TABLE), or disable it for bulk operations if that is safe.", name)
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "Trigger overhead dominates DML",
+			Description:    description,
+			Recommendation: recommendation,
+			Severity:       severity,
+		})
+	}
+
+	return suggestions, nil
+}
+
+func loadForeignKeyIndex(ctx context.Context, db Querier, constraint, referenced string) (foreignKeyIndex, bool) {
+	var fk foreignKeyIndex
+	if db == nil || constraint == "" || referenced == "" {
+		return fk, false
+	}
+
+	if err := db.QueryRow(ctx, foreignKeyIndexQuery, constraint, referenced).Scan(&fk.table, &fk.columns, &fk.indexed); err != nil {
+		return fk, false
+	}
+	return fk, true
+}
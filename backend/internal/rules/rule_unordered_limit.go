@@ -0,0 +1,74 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
+)
+
+type UnorderedLimitRule struct{}
+
+func NewUnorderedLimitRule() *UnorderedLimitRule {
+	return &UnorderedLimitRule{}
+}
+
+func (r *UnorderedLimitRule) Name() string {
+	return "UnorderedLimit"
+}
+
+func (r *UnorderedLimitRule) Apply(_ context.Context, input Input) ([]types.Suggestion, error) {
+	suggestions := make([]types.Suggestion, 0)
+
+	walkSelectStmts(input.AST, "query", func(stmt map[string]any, scope string) {
+		limit, ok := stmt["limitCount"]
+		if !ok {
+			return
+		}
+		if _, sorted := stmt["sortClause"]; sorted {
+			return
+		}
+		if aConst, ok := ChildNode(limit, "A_Const"); ok {
+			// LIMIT ALL and LIMIT NULL do not limit anything.
+			if isNull, _ := aConst["isnull"].(bool); isNull {
+				return
+			}
+		}
+		// Existence checks such as SELECT 1 ... LIMIT 1 return the same
+		// result whichever row is picked.
+		if constantTargets(stmt) {
+			return
+		}
+
+		limitText := "LIMIT"
+		if count, ok := extractConstInt(limit); ok {
+			limitText = fmt.Sprintf("LIMIT %d", count)
+		}
+
+		suggestions = append(suggestions, types.Suggestion{
+			Title:          "LIMIT without ORDER BY",
+			Description:    fmt.Sprintf("The %s uses %s without ORDER BY, so which rows are returned depends on the physical row order and the chosen plan and can change between runs.", scope, limitText),
+			Recommendation: "Add an ORDER BY on a unique key (for example the primary key) so the result is deterministic and pagination is stable.",
+			Severity:       types.SeverityLow,
+		})
+	})
+
+	return suggestions, nil
+}
+
+func constantTargets(stmt map[string]any) bool {
+	targets, _ := stmt["targetList"].([]any)
+	if len(targets) == 0 {
+		return false
+	}
+	for _, target := range targets {
+		resTarget, ok := ChildNode(target, "ResTarget")
+		if !ok {
+			return false
+		}
+		if _, ok := ChildNode(resTarget["val"], "A_Const"); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -2,7 +2,17 @@ package rules
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	pgquery "github.com/pganalyze/pg_query_go/v5"
 
 	"github.com/evgeny/sql-opti-viz/backend/pkg/types"
 )
@@ -12,7 +22,7 @@ func TestSeqScanRule(t *testing.T) {
 	input := Input{
 		Plan: map[string]any{
 			"Plan": map[string]any{
-				"Node Type":    "Seq Scan",
+				"Node Type":     "Seq Scan",
 				"Relation Name": "users",
 			},
 		},
@@ -143,7 +153,7 @@ func TestEngineAggregatesRules(t *testing.T) {
 	input := Input{
 		Plan: map[string]any{
 			"Plan": map[string]any{
-				"Node Type":    "Seq Scan",
+				"Node Type":     "Seq Scan",
 				"Relation Name": "users",
 			},
 		},
@@ -195,5 +205,1555 @@ func TestEngineAggregatesRules(t *testing.T) {
 	}
 }
 
+func TestRowEstimateRule(t *testing.T) {
+	rule := NewRowEstimateRule()
+	input := Input{
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":    "Hash Join",
+				"Plan Rows":    float64(10),
+				"Actual Rows":  float64(5000),
+				"Actual Loops": float64(1),
+				"Hash Cond":    "(o.user_id = u.id)",
+				"Plans": []any{
+					map[string]any{
+						"Node Type":     "Seq Scan",
+						"Relation Name": "users",
+						"Plan Rows":     float64(1000),
+						"Actual Rows":   float64(1000),
+						"Actual Loops":  float64(1),
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected severity High, got %s", suggestions[0].Severity)
+	}
+}
+
+func TestDiskSpillRule(t *testing.T) {
+	rule := NewDiskSpillRule()
+	input := Input{
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":       "Sort",
+				"Sort Method":     "external merge",
+				"Sort Space Used": float64(20000),
+				"Sort Space Type": "Disk",
+				"Plans": []any{
+					map[string]any{
+						"Node Type":             "Hash",
+						"Hash Batches":          float64(1),
+						"Original Hash Batches": float64(1),
+						"Peak Memory Usage":     float64(48),
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "SET LOCAL work_mem = '32MB'") {
+		t.Fatalf("expected work_mem recommendation, got %q", suggestions[0].Recommendation)
+	}
+}
+
+func TestSeqScanRuleIgnoresSmallTables(t *testing.T) {
+	rule := NewSeqScanRule()
+	input := Input{
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":              "Seq Scan",
+				"Relation Name":          "countries",
+				"Total Cost":             float64(1.1),
+				"Plan Rows":              float64(10),
+				"Actual Rows":            float64(1),
+				"Actual Loops":           float64(1),
+				"Rows Removed by Filter": float64(9),
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 0 {
+		t.Fatalf("expected no suggestions, got %d", len(suggestions))
+	}
+}
+
+func TestSeqScanRuleEscalatesSelectiveScan(t *testing.T) {
+	rule := NewSeqScanRule()
+	input := Input{
+		Plan: map[string]any{
+			"Execution Time": float64(120),
+			"Plan": map[string]any{
+				"Node Type":              "Seq Scan",
+				"Relation Name":          "orders",
+				"Total Cost":             float64(25000),
+				"Plan Rows":              float64(40),
+				"Actual Rows":            float64(35),
+				"Actual Loops":           float64(1),
+				"Actual Total Time":      float64(110),
+				"Rows Removed by Filter": float64(999965),
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected severity High, got %s", suggestions[0].Severity)
+	}
+}
+
+func TestSeqScanRuleLabelsEstimatedRows(t *testing.T) {
+	rule := NewSeqScanRule()
+	input := Input{
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":     "Seq Scan",
+				"Relation Name": "orders",
+				"Total Cost":    float64(25000),
+				"Plan Rows":     float64(40),
+				"Filter":        "(status = 'refunded'::text)",
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if !strings.Contains(suggestions[0].Description, "estimated output of 40 rows") || strings.Contains(suggestions[0].Description, "reading about") {
+		t.Fatalf("expected the plan estimate to be labeled as output, got %q", suggestions[0].Description)
+	}
+}
+
+func loadFixturePlan(t *testing.T, name string) map[string]any {
+	t.Helper()
+
+	raw, err := os.ReadFile(filepath.Join("..", "..", "..", "fixtures", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	var payload []map[string]any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+	if len(payload) == 0 {
+		t.Fatalf("fixture %s is empty", name)
+	}
+	return payload[0]
+}
+
+func TestFilterSelectivityRule(t *testing.T) {
+	rule := NewFilterSelectivityRule()
+	input := Input{Plan: loadFixturePlan(t, "complex_manual_explain.json")}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	recommendation := suggestions[0].Recommendation
+	if !strings.Contains(recommendation, "orders_status_idx") || !strings.Contains(recommendation, "orders (status, created_at)") {
+		t.Fatalf("expected recommendation to extend orders_status_idx, got %q", recommendation)
+	}
+}
+
+func TestNestedLoopRule(t *testing.T) {
+	rule := NewNestedLoopRule()
+	input := Input{
+		Plan: map[string]any{
+			"Execution Time": float64(2500),
+			"Plan": map[string]any{
+				"Node Type":   "Nested Loop",
+				"Join Filter": "(o.id = i.order_id)",
+				"Plans": []any{
+					map[string]any{
+						"Node Type":           "Seq Scan",
+						"Parent Relationship": "Outer",
+						"Relation Name":       "orders",
+						"Plan Rows":           float64(5000),
+						"Actual Rows":         float64(5000),
+						"Actual Loops":        float64(1),
+					},
+					map[string]any{
+						"Node Type":           "Seq Scan",
+						"Parent Relationship": "Inner",
+						"Relation Name":       "order_items",
+						"Alias":               "i",
+						"Total Cost":          float64(35),
+						"Actual Total Time":   float64(0.45),
+						"Actual Rows":         float64(3),
+						"Actual Loops":        float64(5000),
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected severity High, got %s", suggestions[0].Severity)
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "order_items (order_id)") {
+		t.Fatalf("expected index recommendation on inner join key, got %q", suggestions[0].Recommendation)
+	}
+}
+
+func parseTestAST(t *testing.T, query string) map[string]any {
+	t.Helper()
+
+	raw, err := pgquery.ParseToJSON(query)
+	if err != nil {
+		t.Fatalf("parse query: %v", err)
+	}
+
+	var ast map[string]any
+	if err := json.Unmarshal([]byte(raw), &ast); err != nil {
+		t.Fatalf("decode AST: %v", err)
+	}
+	return ast
+}
+
+func TestSelectStarRule(t *testing.T) {
+	rule := NewSelectStarRule()
+	input := Input{
+		AST: parseTestAST(t, `WITH recent AS (SELECT * FROM orders)
+SELECT r.id, r.total FROM recent r JOIN (SELECT u.* FROM users u) s ON s.id = r.user_id`),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":  "Hash Join",
+				"Plan Width": float64(16),
+				"Plans": []any{
+					map[string]any{
+						"Node Type":           "Seq Scan",
+						"Parent Relationship": "InitPlan",
+						"Subplan Name":        "CTE recent",
+						"Relation Name":       "orders",
+						"Plan Width":          float64(120),
+						"Actual Rows":         float64(1000),
+						"Actual Loops":        float64(1),
+					},
+					map[string]any{
+						"Node Type":    "CTE Scan",
+						"CTE Name":     "recent",
+						"Plan Width":   float64(24),
+						"Actual Rows":  float64(1000),
+						"Actual Loops": float64(1),
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityMedium || !strings.Contains(suggestions[0].Description, "only uses id, total, user_id") {
+		t.Fatalf("expected CTE column usage finding, got %+v", suggestions[0])
+	}
+
+	if !strings.Contains(suggestions[0].Description, "so 96 bytes per row") {
+		t.Fatalf("expected wasted bytes per row, got %q", suggestions[0].Description)
+	}
+
+	if !strings.Contains(suggestions[1].Description, "subquery s") {
+		t.Fatalf("expected subquery finding, got %q", suggestions[1].Description)
+	}
+}
+
+func TestSelectStarRuleUnqualifiedJoinColumns(t *testing.T) {
+	rule := NewSelectStarRule()
+	input := Input{
+		AST: parseTestAST(t, `WITH recent AS (SELECT * FROM orders)
+SELECT r.id, total FROM recent r JOIN users u ON u.id = r.user_id`),
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	for _, suggestion := range suggestions {
+		if suggestion.Title == "CTE selects unused columns" {
+			t.Fatalf("expected no column list when an unqualified column may come from the CTE, got %q", suggestion.Recommendation)
+		}
+	}
+
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0].Description, "CTE recent") {
+		t.Fatalf("expected a plain SELECT * finding for the CTE, got %+v", suggestions)
+	}
+}
+
+func TestNotInSubqueryRule(t *testing.T) {
+	rule := NewNotInSubqueryRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT id, email FROM users u WHERE id NOT IN (SELECT user_id FROM banned WHERE active)"),
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	expected := "SELECT id, email FROM users u WHERE NOT EXISTS (SELECT 1 FROM banned banned_sub WHERE active AND banned_sub.user_id = u.id)"
+	if suggestions[0].Rewrite != expected {
+		t.Fatalf("unexpected rewrite:\n got: %s\nwant: %s", suggestions[0].Rewrite, expected)
+	}
+}
+
+func TestNotInSubqueryRuleRewriteScoping(t *testing.T) {
+	rule := NewNotInSubqueryRule()
+	tests := []struct {
+		name    string
+		query   string
+		rewrite string
+	}{
+		{
+			name:    "self reference",
+			query:   "SELECT id FROM users WHERE id NOT IN (SELECT users.manager_id FROM users WHERE users.active)",
+			rewrite: "SELECT id FROM users WHERE NOT EXISTS (SELECT 1 FROM users users_sub WHERE users_sub.active AND users_sub.manager_id = users.id)",
+		},
+		{
+			name:    "ambiguous outer column",
+			query:   "SELECT o.id FROM orders o, customers c WHERE o.customer_id = c.id AND status NOT IN (SELECT status FROM archived_statuses)",
+			rewrite: "",
+		},
+		{
+			name:    "limited subquery",
+			query:   "SELECT id FROM users WHERE id NOT IN (SELECT user_id FROM banned ORDER BY banned_at DESC LIMIT 10)",
+			rewrite: "",
+		},
+		{
+			name:    "qualified outer column",
+			query:   "SELECT o.id FROM orders o, customers c WHERE o.customer_id = c.id AND o.status NOT IN (SELECT status FROM archived_statuses)",
+			rewrite: "SELECT o.id FROM orders o, customers c WHERE o.customer_id = c.id AND NOT EXISTS (SELECT 1 FROM archived_statuses archived_statuses_sub WHERE archived_statuses_sub.status = o.status)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions, err := rule.Apply(context.Background(), Input{AST: parseTestAST(t, tt.query)})
+			if err != nil {
+				t.Fatalf("apply rule: %v", err)
+			}
+			if len(suggestions) != 1 {
+				t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+			}
+			if suggestions[0].Rewrite != tt.rewrite {
+				t.Fatalf("unexpected rewrite:\n got: %s\nwant: %s", suggestions[0].Rewrite, tt.rewrite)
+			}
+		})
+	}
+}
+
+func TestOffsetPaginationRule(t *testing.T) {
+	rule := NewOffsetPaginationRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT id, title FROM posts WHERE author_id = $1 ORDER BY created_at DESC, id DESC LIMIT 20 OFFSET 50000"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":   "Limit",
+				"Actual Rows": float64(20),
+				"Plans": []any{
+					map[string]any{
+						"Node Type":   "Index Scan",
+						"Actual Rows": float64(50020),
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected severity High, got %s", suggestions[0].Severity)
+	}
+
+	if !strings.Contains(suggestions[0].Description, "discarded 50000 rows") {
+		t.Fatalf("expected discarded rows in description, got %q", suggestions[0].Description)
+	}
+
+	expected := "SELECT id, title FROM posts WHERE author_id = $1 AND (created_at, id) < ($2, $3) ORDER BY created_at DESC, id DESC LIMIT 20"
+	if suggestions[0].Rewrite != expected {
+		t.Fatalf("unexpected rewrite:\n got: %s\nwant: %s", suggestions[0].Rewrite, expected)
+	}
+}
+
+func TestOffsetPaginationRuleQuotedIdentifiers(t *testing.T) {
+	rule := NewOffsetPaginationRule()
+	input := Input{
+		AST: parseTestAST(t, `SELECT id FROM events ORDER BY "Created At" DESC, "order" LIMIT 20 OFFSET 5000`),
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
 
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
 
+	expected := `SELECT id FROM events WHERE "Created At" < $1 OR ("Created At" = $1 AND "order" > $2) ORDER BY "Created At" DESC, "order" LIMIT 20`
+	if suggestions[0].Rewrite != expected {
+		t.Fatalf("unexpected rewrite:\n got: %s\nwant: %s", suggestions[0].Rewrite, expected)
+	}
+}
+
+func TestCartesianJoinRule(t *testing.T) {
+	rule := NewCartesianJoinRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT * FROM orders o, customers c, regions r WHERE o.region_id = r.id AND o.status = 'paid'"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":    "Nested Loop",
+				"Actual Rows":  float64(250000),
+				"Actual Loops": float64(1),
+				"Plans": []any{
+					map[string]any{
+						"Node Type":           "Hash Join",
+						"Parent Relationship": "Outer",
+						"Hash Cond":           "(o.region_id = r.id)",
+						"Plans": []any{
+							map[string]any{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o"},
+							map[string]any{"Node Type": "Seq Scan", "Relation Name": "regions", "Alias": "r"},
+						},
+					},
+					map[string]any{
+						"Node Type":           "Materialize",
+						"Parent Relationship": "Inner",
+						"Plans": []any{
+							map[string]any{"Node Type": "Seq Scan", "Relation Name": "customers", "Alias": "c"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected severity High, got %s", suggestions[0].Severity)
+	}
+
+	if !strings.Contains(suggestions[0].Description, "o and c") {
+		t.Fatalf("expected both relations in description, got %q", suggestions[0].Description)
+	}
+}
+
+func TestCartesianJoinRuleIgnoresJoinedRelations(t *testing.T) {
+	rule := NewCartesianJoinRule()
+	queries := []string{
+		"SELECT * FROM orders o JOIN customers c ON c.id = o.customer_id, regions r WHERE r.id = c.region_id",
+		"SELECT * FROM parcels a, zones b WHERE st_intersects(a.geom, b.geom)",
+		"SELECT * FROM orders o JOIN customers c ON (c.id = o.customer_id OR c.legacy_id = o.customer_id)",
+		"SELECT * FROM orders o, customers c WHERE EXISTS (SELECT 1 FROM links l WHERE l.order_id = o.id AND l.customer_id = c.id)",
+	}
+
+	for _, query := range queries {
+		suggestions, err := rule.Apply(context.Background(), Input{AST: parseTestAST(t, query)})
+		if err != nil {
+			t.Fatalf("apply rule: %v", err)
+		}
+
+		if len(suggestions) != 0 {
+			t.Fatalf("%s: expected no suggestions, got %d", query, len(suggestions))
+		}
+	}
+}
+
+func TestCorrelatedSubPlanRule(t *testing.T) {
+	rule := NewCorrelatedSubPlanRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT u.id, (SELECT max(o.created_at) FROM orders o WHERE o.user_id = u.id) AS last_order FROM users u"),
+		Plan: map[string]any{
+			"Execution Time": float64(900),
+			"Plan": map[string]any{
+				"Node Type":     "Seq Scan",
+				"Relation Name": "users",
+				"Alias":         "u",
+				"Plans": []any{
+					map[string]any{
+						"Node Type":           "Aggregate",
+						"Parent Relationship": "SubPlan",
+						"Subplan Name":        "SubPlan 1",
+						"Actual Loops":        float64(10000),
+						"Actual Total Time":   float64(0.08),
+						"Plans": []any{
+							map[string]any{
+								"Node Type":     "Seq Scan",
+								"Relation Name": "orders",
+								"Alias":         "o",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected severity High, got %s", suggestions[0].Severity)
+	}
+
+	if !strings.Contains(suggestions[0].Description, "correlated through u.id") {
+		t.Fatalf("expected correlation in description, got %q", suggestions[0].Description)
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "up to 799.9 ms") {
+		t.Fatalf("expected estimated saving, got %q", suggestions[0].Recommendation)
+	}
+}
+
+func TestCorrelatedSubPlanRuleStableMatch(t *testing.T) {
+	rule := NewCorrelatedSubPlanRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT u.id FROM users u WHERE (SELECT count(*) FROM orders o WHERE o.user_id = u.id) > (SELECT count(*) FROM orders o2 WHERE o2.account_id = u.account_id)"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":     "Seq Scan",
+				"Relation Name": "users",
+				"Alias":         "u",
+				"Plans": []any{
+					map[string]any{
+						"Node Type":           "Aggregate",
+						"Parent Relationship": "SubPlan",
+						"Subplan Name":        "SubPlan 1",
+						"Actual Loops":        float64(10000),
+						"Actual Total Time":   float64(0.05),
+						"Plans": []any{
+							map[string]any{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Both subqueries read orders, so the match is a tie decided by the
+	// order the sublinks were collected in.
+	for i := 0; i < 20; i++ {
+		suggestions, err := rule.Apply(context.Background(), input)
+		if err != nil {
+			t.Fatalf("apply rule: %v", err)
+		}
+		if len(suggestions) != 1 {
+			t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+		}
+		if !strings.Contains(suggestions[0].Description, "correlated through u.id.") {
+			t.Fatalf("run %d: expected the first sublink to match, got %q", i, suggestions[0].Description)
+		}
+	}
+}
+
+func TestBufferUsageRule(t *testing.T) {
+	rule := NewBufferUsageRule()
+	input := Input{
+		Plan: map[string]any{
+			"Planning": map[string]any{
+				"Shared Hit Blocks":  float64(200),
+				"Shared Read Blocks": float64(12),
+			},
+			"Plan": map[string]any{
+				"Node Type":           "Hash Join",
+				"Shared Hit Blocks":   float64(600),
+				"Shared Read Blocks":  float64(5000),
+				"Temp Read Blocks":    float64(0),
+				"Temp Written Blocks": float64(0),
+				"Plans": []any{
+					map[string]any{
+						"Node Type":          "Seq Scan",
+						"Relation Name":      "events",
+						"Shared Hit Blocks":  float64(100),
+						"Shared Read Blocks": float64(4990),
+					},
+					map[string]any{
+						"Node Type":          "Seq Scan",
+						"Relation Name":      "users",
+						"Shared Hit Blocks":  float64(500),
+						"Shared Read Blocks": float64(10),
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityMedium || !strings.Contains(suggestions[0].Description, "10.7% cache hit ratio") {
+		t.Fatalf("expected query-level cache summary, got %+v", suggestions[0])
+	}
+
+	if !strings.Contains(suggestions[1].Description, "Seq Scan on events") {
+		t.Fatalf("expected heavy read finding on events, got %q", suggestions[1].Description)
+	}
+}
+
+func TestBufferUsageRuleLeavesSpillsToDiskSpillRule(t *testing.T) {
+	rule := NewBufferUsageRule()
+	input := Input{
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":           "Sort",
+				"Shared Hit Blocks":   float64(900),
+				"Sort Method":         "external merge",
+				"Sort Space Type":     "Disk",
+				"Sort Space Used":     float64(52000),
+				"Temp Read Blocks":    float64(6500),
+				"Temp Written Blocks": float64(6500),
+				"Plans": []any{
+					map[string]any{
+						"Node Type":           "Hash Join",
+						"Temp Read Blocks":    float64(3000),
+						"Temp Written Blocks": float64(3000),
+						"Plans": []any{
+							map[string]any{"Node Type": "Seq Scan", "Relation Name": "events"},
+							map[string]any{
+								"Node Type":             "Hash",
+								"Hash Batches":          float64(8),
+								"Original Hash Batches": float64(1),
+								"Plans": []any{
+									map[string]any{"Node Type": "Seq Scan", "Relation Name": "users"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 || suggestions[0].Title != "Buffer and cache usage" {
+		t.Fatalf("expected only the query-level buffer summary, got %+v", suggestions)
+	}
+}
+
+func TestBufferUsageRuleQuietOnWarmFixture(t *testing.T) {
+	rule := NewBufferUsageRule()
+	input := Input{Plan: loadFixturePlan(t, "complex_manual_explain.json")}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 0 {
+		t.Fatalf("expected no suggestions, got %d", len(suggestions))
+	}
+}
+
+type fakeRow struct {
+	values []any
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	for i, target := range dest {
+		switch typed := target.(type) {
+		case *int64:
+			*typed = r.values[i].(int64)
+		case *string:
+			*typed = r.values[i].(string)
+		case *bool:
+			*typed = r.values[i].(bool)
+		case **time.Time:
+			if value, ok := r.values[i].(time.Time); ok {
+				*typed = &value
+			}
+		}
+	}
+	return nil
+}
+
+type fakeQuerier struct {
+	row  fakeRow
+	args []any
+}
+
+func (q *fakeQuerier) QueryRow(_ context.Context, _ string, args ...any) pgx.Row {
+	q.args = args
+	return q.row
+}
+
+func TestIndexOnlyHeapFetchRule(t *testing.T) {
+	rule := NewIndexOnlyHeapFetchRule()
+	db := &fakeQuerier{row: fakeRow{values: []any{int64(80000), int64(24000), nil, time.Date(2026, 9, 1, 3, 0, 0, 0, time.UTC)}}}
+	input := Input{
+		DB: db,
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":     "Index Only Scan",
+				"Relation Name": "orders",
+				"Index Name":    "orders_user_id_created_at_idx",
+				"Actual Rows":   float64(20000),
+				"Actual Loops":  float64(1),
+				"Heap Fetches":  float64(18000),
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected severity High, got %s", suggestions[0].Severity)
+	}
+
+	if len(db.args) != 1 || db.args[0] != "orders" {
+		t.Fatalf("expected stats lookup for orders, got %v", db.args)
+	}
+
+	if !strings.Contains(suggestions[0].Description, "24000 dead and 80000 live tuples in orders, which was last vacuumed 2026-09-01T03:00:00Z") {
+		t.Fatalf("expected vacuum statistics in description, got %q", suggestions[0].Description)
+	}
+}
+
+func TestParallelQueryRule(t *testing.T) {
+	rule := NewParallelQueryRule()
+	input := Input{
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type": "Append",
+				"Plans": []any{
+					map[string]any{
+						"Node Type":        "Gather",
+						"Workers Planned":  float64(4),
+						"Workers Launched": float64(0),
+						"Plans": []any{
+							map[string]any{
+								"Node Type":      "Seq Scan",
+								"Relation Name":  "events",
+								"Parallel Aware": true,
+								"Total Cost":     float64(90000),
+							},
+						},
+					},
+					map[string]any{
+						"Node Type":      "Seq Scan",
+						"Relation Name":  "events_archive",
+						"Parallel Aware": false,
+						"Total Cost":     float64(120000),
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh || !strings.Contains(suggestions[0].Recommendation, "max_worker_processes") {
+		t.Fatalf("expected unlaunched workers finding, got %+v", suggestions[0])
+	}
+
+	if !strings.Contains(suggestions[1].Description, "events_archive") {
+		t.Fatalf("expected serial scan finding on events_archive, got %q", suggestions[1].Description)
+	}
+}
+
+func TestPlanningOverheadRule(t *testing.T) {
+	rule := NewPlanningOverheadRule()
+	input := Input{
+		Plan: map[string]any{
+			"Planning Time":  float64(48.2),
+			"Execution Time": float64(130.5),
+			"JIT": map[string]any{
+				"Functions": float64(42),
+				"Timing": map[string]any{
+					"Generation": float64(6.1),
+					"Total":      float64(95.4),
+				},
+			},
+			"Plan": map[string]any{
+				"Node Type":        "Append",
+				"Subplans Removed": float64(60),
+				"Plans": []any{
+					map[string]any{"Node Type": "Seq Scan", "Relation Name": "events_2026_09"},
+					map[string]any{"Node Type": "Seq Scan", "Relation Name": "events_2026_10"},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "jit_above_cost") {
+		t.Fatalf("expected JIT recommendation, got %q", suggestions[0].Recommendation)
+	}
+
+	input.Plan["Planning Time"] = float64(480)
+	suggestions, err = rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got %d", len(suggestions))
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "prepared statements") || !strings.Contains(suggestions[0].Recommendation, "pruning 60") {
+		t.Fatalf("expected planning recommendation with partition pruning, got %q", suggestions[0].Recommendation)
+	}
+}
+
+func TestColumnCastRule(t *testing.T) {
+	rule := NewColumnCastRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT id FROM orders o WHERE o.created_at::date = '2024-01-01' AND CAST(customer_id AS text) = $1"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":     "Seq Scan",
+				"Relation Name": "orders",
+				"Alias":         "o",
+				"Filter":        "(((o.created_at)::date = '2024-01-01'::date) AND ((customer_id)::text = $1) AND ((code)::text = 'X1'::text) AND ((qty)::numeric > 1.5))",
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %d", len(suggestions))
+	}
+
+	if want := "o.created_at >= '2024-01-01' AND o.created_at < '2024-01-01'::date + 1"; !strings.Contains(suggestions[0].Recommendation, want) {
+		t.Fatalf("expected range rewrite %q, got %q", want, suggestions[0].Recommendation)
+	}
+
+	if !strings.Contains(suggestions[1].Description, "customer_id is cast to text") {
+		t.Fatalf("unexpected description %q", suggestions[1].Description)
+	}
+
+	if suggestions[2].Severity != types.SeverityLow || !strings.Contains(suggestions[2].Description, "(qty)::numeric") {
+		t.Fatalf("expected low severity plan cast on qty without the varchar relabel on code, got %+v", suggestions[2])
+	}
+}
+
+func TestArithmeticOnColumnRule(t *testing.T) {
+	rule := NewArithmeticOnColumnRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT id FROM orders WHERE price * 1.2 > 100 AND created_at + interval '1 day' > now() AND 10 < qty * 2 AND total / qty > 5"),
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 4 {
+		t.Fatalf("expected 4 suggestions, got %d", len(suggestions))
+	}
+
+	expected := []string{
+		"price > (100 / 1.2)",
+		"created_at > (now() - '1 day'::interval)",
+		"qty > (10 / 2::numeric)",
+		"Keep total alone",
+	}
+	for i, want := range expected {
+		if !strings.Contains(suggestions[i].Recommendation, want) {
+			t.Fatalf("suggestion %d: expected %q in %q", i, want, suggestions[i].Recommendation)
+		}
+	}
+}
+
+func TestArithmeticOnColumnRuleNegativeLiteral(t *testing.T) {
+	rule := NewArithmeticOnColumnRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT id FROM orders WHERE price * -2 > 100"),
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if strings.Contains(suggestions[0].Description, "* 0") {
+		t.Fatalf("description shows a mangled literal: %q", suggestions[0].Description)
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "Keep price alone") {
+		t.Fatalf("expected text-only recommendation, got %q", suggestions[0].Recommendation)
+	}
+}
+
+func TestOrPredicateRuleRewriteOnlyTopLevel(t *testing.T) {
+	rule := NewOrPredicateRule()
+	queries := []string{
+		"SELECT id FROM users WHERE NOT (email = $1 OR phone = $2)",
+		"SELECT id FROM users WHERE active OR (deleted AND (email = $1 OR phone = $2))",
+	}
+
+	for _, query := range queries {
+		suggestions, err := rule.Apply(context.Background(), Input{AST: parseTestAST(t, query)})
+		if err != nil {
+			t.Fatalf("apply rule: %v", err)
+		}
+		found := false
+		for _, suggestion := range suggestions {
+			if !strings.Contains(suggestion.Description, "(email, phone)") {
+				continue
+			}
+			found = true
+			if suggestion.Rewrite != "" {
+				t.Fatalf("%s: expected no rewrite for a nested OR, got %q", query, suggestion.Rewrite)
+			}
+		}
+		if !found {
+			t.Fatalf("%s: expected a suggestion for the nested OR", query)
+		}
+	}
+}
+
+func TestOrPredicateRule(t *testing.T) {
+	rule := NewOrPredicateRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT id FROM users WHERE active AND (email = $1 OR phone = $2)"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":     "Seq Scan",
+				"Relation Name": "users",
+				"Filter":        "(active AND ((email = $1) OR (phone = $2)))",
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityMedium {
+		t.Fatalf("expected medium severity, got %s", suggestions[0].Severity)
+	}
+
+	want := "SELECT id FROM users WHERE active AND email = $1 UNION ALL SELECT id FROM users WHERE active AND (phone = $2 AND email = $1 IS NOT TRUE)"
+	if suggestions[0].Rewrite != want {
+		t.Fatalf("unexpected rewrite:\n got %s\nwant %s", suggestions[0].Rewrite, want)
+	}
+
+	input.Plan = map[string]any{
+		"Plan": map[string]any{
+			"Node Type":     "Bitmap Heap Scan",
+			"Relation Name": "users",
+			"Plans": []any{
+				map[string]any{"Node Type": "BitmapOr"},
+			},
+		},
+	}
+	suggestions, err = rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 0 {
+		t.Fatalf("expected no suggestions when BitmapOr is used, got %d", len(suggestions))
+	}
+
+	input = Input{AST: parseTestAST(t, "SELECT id FROM users WHERE status = 'a' OR status = 'b'")}
+	suggestions, err = rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 0 {
+		t.Fatalf("expected no suggestions for OR on a single column, got %d", len(suggestions))
+	}
+}
+
+func TestLargeInListRule(t *testing.T) {
+	values := make([]string, 0, 1200)
+	for i := range 1200 {
+		values = append(values, strconv.Itoa(i))
+	}
+	query := fmt.Sprintf("SELECT id FROM orders WHERE tenant_id = $1 AND customer_id IN (%s) AND status IN ('new', 'paid')", strings.Join(values, ", "))
+
+	rule := NewLargeInListRule()
+	input := Input{
+		AST: parseTestAST(t, query),
+		Plan: map[string]any{
+			"Planning Time":  float64(35.4),
+			"Execution Time": float64(80.1),
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected high severity, got %s", suggestions[0].Severity)
+	}
+
+	if !strings.Contains(suggestions[0].Description, "1200 values") || !strings.Contains(suggestions[0].Description, "Planning took 35.40 ms") {
+		t.Fatalf("unexpected description %q", suggestions[0].Description)
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "customer_id = ANY($2::bigint[])") {
+		t.Fatalf("unexpected recommendation %q", suggestions[0].Recommendation)
+	}
+}
+
+func TestRedundantDedupRule(t *testing.T) {
+	rule := NewRedundantDedupRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT DISTINCT u.id, u.email FROM users u JOIN orders o ON o.user_id = u.id WHERE o.total > 100"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":         "Unique",
+				"Total Cost":        float64(5200),
+				"Actual Total Time": float64(410.5),
+				"Plans": []any{
+					map[string]any{
+						"Node Type":         "Sort",
+						"Total Cost":        float64(5100),
+						"Actual Total Time": float64(380.0),
+						"Plans": []any{
+							map[string]any{
+								"Node Type":         "Hash Join",
+								"Total Cost":        float64(3800),
+								"Actual Total Time": float64(250.5),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if !strings.Contains(suggestions[0].Description, "Unique over a Sort, costing 1400 units and 160.00 ms") {
+		t.Fatalf("unexpected description %q", suggestions[0].Description)
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "EXISTS") {
+		t.Fatalf("expected EXISTS recommendation, got %q", suggestions[0].Recommendation)
+	}
+
+	input = Input{
+		AST: parseTestAST(t, "SELECT id FROM active_users UNION SELECT id FROM invited_users"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type":  "Aggregate",
+				"Strategy":   "Hashed",
+				"Total Cost": float64(900),
+				"Plans": []any{
+					map[string]any{"Node Type": "Append", "Total Cost": float64(650)},
+				},
+			},
+		},
+	}
+	suggestions, err = rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 || suggestions[0].Title != "UNION instead of UNION ALL" {
+		t.Fatalf("expected UNION suggestion, got %+v", suggestions)
+	}
+
+	if !strings.Contains(suggestions[0].Description, "deduplicates with a HashAggregate, costing 250 units.") {
+		t.Fatalf("unexpected description %q", suggestions[0].Description)
+	}
+}
+
+func TestOrderByRandomRule(t *testing.T) {
+	rule := NewOrderByRandomRule()
+	input := Input{
+		AST: parseTestAST(t, "SELECT a.id, a.title FROM blog.articles a ORDER BY random() LIMIT 5"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type": "Limit",
+				"Plans": []any{
+					map[string]any{
+						"Node Type":    "Sort",
+						"Sort Key":     []any{"(random())"},
+						"Sort Method":  "top-N heapsort",
+						"Actual Rows":  float64(5),
+						"Actual Loops": float64(1),
+						"Plans": []any{
+							map[string]any{
+								"Node Type":     "Seq Scan",
+								"Relation Name": "articles",
+								"Actual Rows":   float64(120000),
+								"Actual Loops":  float64(1),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if !strings.Contains(suggestions[0].Description, "sorts 120000 input rows using top-N heapsort") {
+		t.Fatalf("unexpected description %q", suggestions[0].Description)
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "blog.articles TABLESAMPLE SYSTEM") {
+		t.Fatalf("unexpected recommendation %q", suggestions[0].Recommendation)
+	}
+}
+
+func TestUnorderedLimitRule(t *testing.T) {
+	rule := NewUnorderedLimitRule()
+
+	cases := []struct {
+		query string
+		want  int
+	}{
+		{"SELECT id, email FROM users WHERE active LIMIT 10", 1},
+		{"SELECT id, email FROM users ORDER BY id LIMIT 10", 0},
+		{"SELECT 1 FROM users WHERE email = $1 LIMIT 1", 0},
+		{"SELECT id FROM users LIMIT ALL", 0},
+	}
+	for _, tc := range cases {
+		suggestions, err := rule.Apply(context.Background(), Input{AST: parseTestAST(t, tc.query)})
+		if err != nil {
+			t.Fatalf("apply rule: %v", err)
+		}
+
+		if len(suggestions) != tc.want {
+			t.Fatalf("%s: expected %d suggestions, got %d", tc.query, tc.want, len(suggestions))
+		}
+	}
+}
+
+func TestCTEMaterializationRule(t *testing.T) {
+	rule := NewCTEMaterializationRule()
+	input := Input{
+		AST: parseTestAST(t, `WITH recent_orders AS (
+  SELECT o.user_id, o.gross_amount, o.status, o.created_at
+  FROM orders o
+  WHERE o.created_at >= NOW() - INTERVAL '90 days'
+)
+SELECT u.country, COUNT(*) AS orders_count, SUM(ro.gross_amount) AS total_amount
+FROM users u
+JOIN recent_orders ro ON ro.user_id = u.id
+WHERE ro.status = 'paid'
+GROUP BY u.country`),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type": "HashAggregate",
+				"Plans": []any{
+					map[string]any{
+						"Node Type":           "Seq Scan",
+						"Parent Relationship": "InitPlan",
+						"Subplan Name":        "CTE recent_orders",
+						"Relation Name":       "orders",
+						"Plan Width":          float64(32),
+						"Actual Rows":         float64(250000),
+						"Actual Loops":        float64(1),
+					},
+					map[string]any{
+						"Node Type": "Hash Join",
+						"Plans": []any{
+							map[string]any{
+								"Node Type":              "CTE Scan",
+								"CTE Name":               "recent_orders",
+								"Alias":                  "ro",
+								"Filter":                 "(status = 'paid'::text)",
+								"Rows Removed by Filter": float64(190000),
+								"Actual Loops":           float64(1),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	for _, want := range []string{"referenced once but materialized", "discarding 190000 rows", "250000 rows of 32 bytes, about 7.6 MB"} {
+		if !strings.Contains(suggestions[0].Description, want) {
+			t.Fatalf("expected %q in description %q", want, suggestions[0].Description)
+		}
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "NOT MATERIALIZED") {
+		t.Fatalf("unexpected recommendation %q", suggestions[0].Recommendation)
+	}
+}
+
+func TestTriggerOverheadRule(t *testing.T) {
+	rule := NewTriggerOverheadRule()
+	db := &fakeQuerier{row: fakeRow{values: []any{"orders", "user_id", false}}}
+	input := Input{
+		DB: db,
+		Plan: map[string]any{
+			"Execution Time": float64(5400),
+			"Triggers": []any{
+				map[string]any{
+					"Trigger Name":    "RI_ConstraintTrigger_a_16499",
+					"Constraint Name": "orders_user_id_fkey",
+					"Relation":        "users",
+					"Time":            float64(5100.2),
+					"Calls":           float64(20000),
+				},
+				map[string]any{
+					"Trigger Name": "users_audit",
+					"Relation":     "users",
+					"Time":         float64(120.4),
+					"Calls":        float64(20000),
+				},
+			},
+		},
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if len(db.args) != 2 || db.args[0] != "orders_user_id_fkey" || db.args[1] != "users" {
+		t.Fatalf("expected constraint lookup, got %v", db.args)
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh {
+		t.Fatalf("expected high severity, got %s", suggestions[0].Severity)
+	}
+
+	if !strings.Contains(suggestions[0].Recommendation, "CREATE INDEX CONCURRENTLY ON orders (user_id)") {
+		t.Fatalf("unexpected recommendation %q", suggestions[0].Recommendation)
+	}
+}
+
+func TestDangerousDMLRule(t *testing.T) {
+	rule := NewDangerousDMLRule()
+
+	cases := []struct {
+		query string
+		title string
+	}{
+		{"DELETE FROM orders", "DELETE without WHERE"},
+		{"UPDATE users SET active = false", "UPDATE without WHERE"},
+		{"DELETE FROM orders o USING users u WHERE u.banned", "DELETE ... USING without join condition"},
+		{"INSERT INTO orders_archive SELECT * FROM orders", "Unbounded INSERT ... SELECT"},
+		{"DELETE FROM orders o USING users u WHERE o.user_id = u.id AND u.banned", ""},
+		{"UPDATE users SET active = false WHERE id = $1", ""},
+		{"INSERT INTO orders_archive SELECT * FROM orders WHERE created_at < $1", ""},
+		{"INSERT INTO orders_archive VALUES (1, 2)", ""},
+	}
+	for _, tc := range cases {
+		suggestions, err := rule.Apply(context.Background(), Input{AST: parseTestAST(t, tc.query)})
+		if err != nil {
+			t.Fatalf("apply rule: %v", err)
+		}
+
+		if tc.title == "" {
+			if len(suggestions) != 0 {
+				t.Fatalf("%s: expected no suggestions, got %+v", tc.query, suggestions)
+			}
+			continue
+		}
+		if len(suggestions) != 1 || suggestions[0].Title != tc.title || suggestions[0].Severity != types.SeverityHigh {
+			t.Fatalf("%s: expected high %q, got %+v", tc.query, tc.title, suggestions)
+		}
+	}
+
+	input := Input{
+		AST: parseTestAST(t, "INSERT INTO orders_archive SELECT * FROM orders WHERE created_at < $1"),
+		Plan: map[string]any{
+			"Plan": map[string]any{
+				"Node Type": "ModifyTable",
+				"Operation": "Insert",
+				"Plans": []any{
+					map[string]any{"Node Type": "Seq Scan", "Relation Name": "orders", "Actual Rows": float64(2500000), "Actual Loops": float64(1)},
+				},
+			},
+		},
+	}
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0].Description, "2500000 rows") {
+		t.Fatalf("expected unbounded insert from plan rows, got %+v", suggestions)
+	}
+}
+
+func TestIndexConcurrentlyRule(t *testing.T) {
+	rule := NewIndexConcurrentlyRule()
+
+	cases := map[string]int{
+		"CREATE INDEX orders_user_id_idx ON orders (user_id)":                                   1,
+		"CREATE INDEX CONCURRENTLY orders_user_id_idx ON orders (user_id)":                      0,
+		"CREATE TABLE events (id bigint, kind text); CREATE INDEX events_kind ON events (kind)": 0,
+	}
+	for query, want := range cases {
+		suggestions, err := rule.Apply(context.Background(), Input{AST: parseTestAST(t, query)})
+		if err != nil {
+			t.Fatalf("apply rule: %v", err)
+		}
+
+		if len(suggestions) != want {
+			t.Fatalf("%s: expected %d suggestions, got %d", query, want, len(suggestions))
+		}
+	}
+}
+
+func TestNotNullDefaultRule(t *testing.T) {
+	rule := NewNotNullDefaultRule()
+	input := Input{
+		AST: parseTestAST(t, `ALTER TABLE orders
+  ALTER COLUMN status SET NOT NULL,
+  ADD COLUMN token uuid NOT NULL DEFAULT gen_random_uuid(),
+  ADD COLUMN note text NOT NULL,
+  ADD COLUMN source text NOT NULL DEFAULT 'web'`),
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %d", len(suggestions))
+	}
+
+	for i, want := range []string{"VALIDATE CONSTRAINT", "SET DEFAULT for new rows", "constant default"} {
+		if !strings.Contains(suggestions[i].Recommendation, want) {
+			t.Fatalf("suggestion %d: expected %q in %q", i, want, suggestions[i].Recommendation)
+		}
+	}
+
+	if !strings.Contains(suggestions[1].Description, "gen_random_uuid()") {
+		t.Fatalf("unexpected description %q", suggestions[1].Description)
+	}
+}
+
+func TestColumnTypeChangeRule(t *testing.T) {
+	rule := NewColumnTypeChangeRule()
+	input := Input{AST: parseTestAST(t, "ALTER TABLE orders ALTER COLUMN amount TYPE bigint, ALTER COLUMN note TYPE text")}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got %d", len(suggestions))
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh || !strings.Contains(suggestions[0].Description, "TYPE bigint") {
+		t.Fatalf("unexpected first suggestion %+v", suggestions[0])
+	}
+
+	if suggestions[1].Severity != types.SeverityMedium {
+		t.Fatalf("expected medium severity for text, got %s", suggestions[1].Severity)
+	}
+}
+
+func TestConstraintValidationRule(t *testing.T) {
+	rule := NewConstraintValidationRule()
+	input := Input{
+		AST: parseTestAST(t, `ALTER TABLE orders
+  ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id),
+  ADD CONSTRAINT orders_amount_check CHECK (amount > 0) NOT VALID,
+  ADD CONSTRAINT orders_code_key UNIQUE (code)`),
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got %d", len(suggestions))
+	}
+
+	if !strings.Contains(suggestions[0].Description, "ShareRowExclusiveLock on both orders and users") {
+		t.Fatalf("unexpected description %q", suggestions[0].Description)
+	}
+
+	if !strings.Contains(suggestions[1].Recommendation, "ADD CONSTRAINT orders_code_key UNIQUE USING INDEX") {
+		t.Fatalf("unexpected recommendation %q", suggestions[1].Recommendation)
+	}
+}
+
+func TestRenameObjectRule(t *testing.T) {
+	rule := NewRenameObjectRule()
+	db := &fakeQuerier{row: fakeRow{values: []any{int64(250000), int64(42000)}}}
+	input := Input{
+		DB:  db,
+		AST: parseTestAST(t, "ALTER TABLE orders RENAME COLUMN amount TO gross_amount"),
+	}
+
+	suggestions, err := rule.Apply(context.Background(), input)
+	if err != nil {
+		t.Fatalf("apply rule: %v", err)
+	}
+
+	if len(suggestions) != 1 {
+		t.Fatalf("expected 1 suggestion, got %d", len(suggestions))
+	}
+
+	if len(db.args) != 1 || db.args[0] != "orders" {
+		t.Fatalf("expected activity lookup for orders, got %v", db.args)
+	}
+
+	if suggestions[0].Severity != types.SeverityHigh || !strings.Contains(suggestions[0].Description, "250000 scans") {
+		t.Fatalf("unexpected suggestion %+v", suggestions[0])
+	}
+}
@@ -32,12 +32,22 @@ type Suggestion struct {
 	Description    string   `json:"description"`
 	Recommendation string   `json:"recommendation"`
 	Severity       Severity `json:"severity"`
+	Rewrite        string   `json:"rewrite,omitempty"`
+}
+
+type LockInfo struct {
+	Statement string `json:"statement"`
+	Relation  string `json:"relation"`
+	Mode      string `json:"mode"`
+	RowLocks  string `json:"row_locks,omitempty"`
+	Warning   string `json:"warning,omitempty"`
 }
 
 type AnalyzeResponse struct {
 	AST         any          `json:"ast"`
 	ExplainPlan any          `json:"explain_plan"`
 	Suggestions []Suggestion `json:"suggestions"`
+	Locks       []LockInfo   `json:"locks"`
 }
 
 type Analyzer interface {
@@ -28,4 +28,22 @@ describe('SuggestionsList', () => {
     expect(screen.getByText(/Add an index/i)).toBeInTheDocument();
     expect(screen.getByText(/High/i)).toBeInTheDocument();
   });
+
+  it('renders the rewritten query when present', () => {
+    render(
+      <SuggestionsList
+        suggestions={[
+          {
+            title: 'NOT IN with subquery',
+            description: 'NOT IN returns no rows when the subquery yields NULL.',
+            recommendation: 'Use NOT EXISTS.',
+            severity: 'Medium',
+            rewrite: 'SELECT * FROM users u WHERE NOT EXISTS (SELECT 1 FROM banned)',
+          },
+        ]}
+      />
+    );
+
+    expect(screen.getByText(/WHERE NOT EXISTS/)).toBeInTheDocument();
+  });
 });
@@ -31,6 +31,11 @@ export function SuggestionsList({ suggestions }: SuggestionsListProps) {
           </header>
           <p className="suggestion-card__description">{item.description}</p>
           <p className="suggestion-card__recommendation">{item.recommendation}</p>
+          {item.rewrite && (
+            <pre className="suggestion-card__rewrite">
+              <code>{item.rewrite}</code>
+            </pre>
+          )}
         </article>
       ))}
     </div>
@@ -24,12 +24,22 @@ export interface Suggestion {
   description: string;
   recommendation: string;
   severity: Severity;
+  rewrite?: string;
+}
+
+export interface LockInfo {
+  statement: string;
+  relation: string;
+  mode: string;
+  row_locks?: string;
+  warning?: string;
 }
 
 export interface AnalyzeResponse {
   ast: unknown;
   explain_plan: unknown;
   suggestions: Suggestion[];
+  locks?: LockInfo[];
 }
 
 export interface AnalyzeError {